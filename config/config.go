@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -12,14 +13,64 @@ import (
 
 type GlobalConfig struct {
 	TelegramBotToken string `envconfig:"TELEGRAM_BOT_TOKEN" required:"true"`
-	GeminiAPIKey     string `envconfig:"GEMINI_API_KEY"     required:"true"`
+	GeminiAPIKey     string `envconfig:"GEMINI_API_KEY"`
 	SuperAdminID     int64  `envconfig:"SUPER_ADMIN_ID"     required:"true"`
 	GlobalScheduleMinutes int    `envconfig:"GLOBAL_SCHEDULE_MINUTES" default:"15"`
+	NewsletterSMTPAddr    string `envconfig:"NEWSLETTER_SMTP_ADDR"`
+	NewsletterSMTPDomain  string `envconfig:"NEWSLETTER_SMTP_DOMAIN" default:"localhost"`
+	OpenAIBaseURL         string `envconfig:"OPENAI_BASE_URL"`
+	OpenAIAPIKey          string `envconfig:"OPENAI_API_KEY"`
+	OllamaBaseURL         string `envconfig:"OLLAMA_BASE_URL"`
+	AIInputTokenBudget    int    `envconfig:"AI_INPUT_TOKEN_BUDGET" default:"30000"`
+	AIModelInputBudgets   string `envconfig:"AI_MODEL_INPUT_BUDGETS"`
+	AIModelPrices         string `envconfig:"AI_MODEL_PRICES"`
+	KeyEncryptionSecret   string `envconfig:"KEY_ENCRYPTION_SECRET"`
+}
+
+// InputTokenBudget returns the maximum prompt size for a model. Budgets are
+// configured as "model=tokens" pairs separated by commas, e.g.
+// "llama3.1:8b=6000,gemini-1.5-flash=200000".
+func (c *GlobalConfig) InputTokenBudget(model string) int {
+	for _, entry := range strings.Split(c.AIModelInputBudgets, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || strings.TrimSpace(name) != model {
+			continue
+		}
+		if budget, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && budget > 0 {
+			return budget
+		}
+	}
+	return c.AIInputTokenBudget
+}
+
+// ModelPrice returns the price in USD per million input and output tokens of
+// a model. Prices are configured as "model=input/output" pairs separated by
+// commas, e.g. "gemini-1.5-flash=0.075/0.30".
+func (c *GlobalConfig) ModelPrice(model string) (input float64, output float64, ok bool) {
+	for _, entry := range strings.Split(c.AIModelPrices, ",") {
+		name, value, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || strings.TrimSpace(name) != model {
+			continue
+		}
+		in, out, found := strings.Cut(value, "/")
+		if !found {
+			continue
+		}
+		input, errIn := strconv.ParseFloat(strings.TrimSpace(in), 64)
+		output, errOut := strconv.ParseFloat(strings.TrimSpace(out), 64)
+		if errIn == nil && errOut == nil {
+			return input, output, true
+		}
+	}
+	return 0, 0, false
 }
 
 type Config struct {
 	AiPrompt                string `json:"ai_prompt"`
-	GeminiModel             string `json:"gemini_model"`
+	AIProvider              string `json:"ai_provider"`
+	// AIModel is the chat's model at AIProvider. It was Gemini-only at first,
+	// hence the GEMINI_MODEL variable for its default.
+	AIModel                 string `json:"ai_model"`
 	TelegramMessageTemplate string `json:"telegram_message_template"`
 	PostLimitPerRun         int    `json:"post_limit_per_run"`
 	EnableApprovalSystem    bool   `json:"enable_approval_system"`
@@ -27,8 +78,100 @@ type Config struct {
 	RSSMaxAgeHours          int    `json:"rss_max_age_hours"`
 	LanguageCode            string `json:"language_code"`
 	ScheduleIntervalMinutes int    `json:"schedule_interval_minutes"`
+	SourceLanguageMode      string `json:"source_language_mode"`
+	SourceLanguages         string `json:"source_languages"`
+	SummaryLanguage         string `json:"summary_language"`
+	TranslateTitle          bool   `json:"translate_title"`
+	MonthlyTokenQuota       int    `json:"monthly_token_quota"`
+	BlockedContentPolicy    string `json:"blocked_content_policy"`
+	ModelFallbacks          string `json:"model_fallbacks"`
+	BYOKProvider            string `json:"byok_provider"`
+	BYOKAPIKey              string `json:"byok_api_key"`
+	DigestEnabled           bool   `json:"digest_enabled"`
+	DigestSchedule          string `json:"digest_schedule"`
+	DigestWindowHours       int    `json:"digest_window_hours"`
+	DigestApproval          bool   `json:"digest_approval"`
+	DigestTemplate          string `json:"digest_template"`
+	ArticleQAEnabled        bool   `json:"article_qa_enabled"`
+	TopicClassification     bool   `json:"topic_classification"`
+	ClassificationThreshold float64 `json:"classification_threshold"`
+	ClassificationFallback  string `json:"classification_fallback"`
+	InterestProfile         string `json:"interest_profile"`
+	RelevanceThreshold      int    `json:"relevance_threshold"`
+	SummaryVerification     string `json:"summary_verification"`
+	SummaryLength           string `json:"summary_length"`
+	SummaryMaxChars         int    `json:"summary_max_chars"`
+	SummaryFormat           string `json:"summary_format"`
+	SummaryEmoji            string `json:"summary_emoji"`
 }
 
+// Source language policies. With LanguageModeAllow only the listed languages
+// are posted, with LanguageModeDeny the listed languages are skipped.
+const (
+	LanguageModeAll   = "all"
+	LanguageModeAllow = "allow"
+	LanguageModeDeny  = "deny"
+)
+
+// SummaryLanguageSource keeps summaries in the article's own language. An
+// empty summary language follows the chat's language_code.
+const SummaryLanguageSource = "source"
+
+// What to do with an article the AI refused to summarize: send it to
+// moderation with the reason, or post it with its description as summary.
+const (
+	BlockedPolicyModeration  = "moderation"
+	BlockedPolicyDescription = "description"
+)
+
+// Where an article goes when AI topic classification is unsure: the topic of
+// its source, or the moderation queue.
+const (
+	ClassificationFallbackSource     = "source"
+	ClassificationFallbackModeration = "moderation"
+	DefaultClassificationThreshold   = 0.6
+)
+
+// How summaries are checked against the article before posting: not at all,
+// by matching their numbers and names in the article text, or by asking a
+// model. Summaries that fail go to moderation.
+const (
+	VerificationOff      = "off"
+	VerificationMatching = "matching"
+	VerificationModel    = "model"
+)
+
+// Summary style settings. With SummaryLengthCustom the limit is the chat's
+// summary_max_chars. The free values leave length, format and emoji use to
+// the AI prompt.
+const (
+	SummaryLengthFree      = "free"
+	SummaryLengthShort     = "short"
+	SummaryLengthMedium    = "medium"
+	SummaryLengthLong      = "long"
+	SummaryLengthCustom    = "custom"
+	SummaryFormatFree      = "free"
+	SummaryFormatParagraph = "paragraph"
+	SummaryFormatBullets   = "bullets"
+	SummaryFormatThread    = "thread"
+	SummaryEmojiFree       = "free"
+	SummaryEmojiNone       = "none"
+	SummaryEmojiFew        = "few"
+	SummaryEmojiMany       = "many"
+)
+
+// DefaultRelevanceThreshold is the lowest relevance score, out of 100, an
+// article needs when the chat has an interest profile.
+const DefaultRelevanceThreshold = 50
+
+// DefaultDigestSchedule posts digests every morning at 08:00, and
+// DefaultDigestTemplate lays them out. The template can use {date}, {intro},
+// {sections}, {count} and {topic_name}.
+const (
+	DefaultDigestSchedule = "0 8 * * *"
+	DefaultDigestTemplate = "<b>🗞 Digest, {date}</b>\n\n{intro}\n\n{sections}"
+)
+
 func LoadGlobalConfig() (*GlobalConfig, error) {
 	err := godotenv.Load()
 	if err != nil {
@@ -49,9 +192,14 @@ func GetDefaultChatConfig() (*Config, error) {
 		log.Println("No .env file found, reading from environment variables for default chat config")
 	}
 
-	geminiModel := os.Getenv("GEMINI_MODEL")
-	if geminiModel == "" {
-		geminiModel = "gemini-1.5-flash"
+	aiProvider := os.Getenv("AI_PROVIDER")
+	if aiProvider == "" {
+		aiProvider = "gemini"
+	}
+
+	aiModel := os.Getenv("GEMINI_MODEL")
+	if aiModel == "" && aiProvider == "gemini" {
+		aiModel = "gemini-1.5-flash"
 	}
 
 	aiPrompt := os.Getenv("AI_PROMPT")
@@ -83,8 +231,16 @@ func GetDefaultChatConfig() (*Config, error) {
 
 	approvalChat, _ := strconv.ParseInt(os.Getenv("APPROVAL_CHAT_ID"), 10, 64)
 
+	monthlyQuota, _ := strconv.Atoi(os.Getenv("MONTHLY_TOKEN_QUOTA"))
+
+	digestTemplate := os.Getenv("DIGEST_TEMPLATE")
+	if digestTemplate == "" {
+		digestTemplate = DefaultDigestTemplate
+	}
+
 	return &Config{
-		GeminiModel:             geminiModel,
+		AIProvider:              aiProvider,
+		AIModel:                 aiModel,
 		AiPrompt:                aiPrompt,
 		TelegramMessageTemplate: template,
 		PostLimitPerRun:         postLimit,
@@ -92,5 +248,19 @@ func GetDefaultChatConfig() (*Config, error) {
 		ApprovalChatID:          approvalChat,
 		RSSMaxAgeHours:          rssMaxAge,
 		ScheduleIntervalMinutes: schedule,
+		SourceLanguageMode:      LanguageModeAll,
+		MonthlyTokenQuota:       monthlyQuota,
+		BlockedContentPolicy:    BlockedPolicyModeration,
+		DigestSchedule:          DefaultDigestSchedule,
+		DigestWindowHours:       24,
+		DigestTemplate:          digestTemplate,
+		ArticleQAEnabled:        false,
+		ClassificationThreshold: DefaultClassificationThreshold,
+		ClassificationFallback:  ClassificationFallbackSource,
+		RelevanceThreshold:      DefaultRelevanceThreshold,
+		SummaryVerification:     VerificationOff,
+		SummaryLength:           SummaryLengthFree,
+		SummaryFormat:           SummaryFormatFree,
+		SummaryEmoji:            SummaryEmojiFree,
 	}, nil
 }
\ No newline at end of file
@@ -0,0 +1,34 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// CacheKey identifies a summary. Chats summarizing the same article with the
+// same provider, model and rendered prompt share a cache entry.
+type CacheKey struct {
+	URL        string
+	Provider   string
+	Model      string
+	PromptHash string
+}
+
+// SummaryCache stores finished summaries so that an article is only paid for
+// once per model and prompt.
+type SummaryCache interface {
+	GetSummary(key CacheKey) (*Summary, bool)
+	PutSummary(key CacheKey, summary *Summary)
+}
+
+// cacheKey builds the key of a request whose rendered prompt is prompt.
+func (s *llmSummarizer) cacheKey(prompt string, req SummaryRequest) CacheKey {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%t", prompt, req.TranslateTitle)))
+	return CacheKey{
+		URL:        req.URL,
+		Provider:   s.generator.Provider(),
+		Model:      s.generator.Model(),
+		PromptHash: hex.EncodeToString(hash[:]),
+	}
+}
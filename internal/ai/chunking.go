@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// charsPerToken is a rough average that holds for English and most Latin
+// script languages; it errs on the side of overestimating.
+const charsPerToken = 4
+
+// EstimateTokens approximates how many tokens a model will count for text.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// splitIntoChunks cuts text into pieces of at most maxTokens, breaking at
+// paragraph and sentence boundaries where possible. Consecutive chunks share
+// about overlapTokens of text so facts spanning a boundary are not lost.
+func splitIntoChunks(text string, maxTokens int, overlapTokens int) []string {
+	var units []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+		if EstimateTokens(paragraph) <= maxTokens {
+			units = append(units, paragraph)
+			continue
+		}
+		units = append(units, splitLongParagraph(paragraph, maxTokens)...)
+	}
+
+	var chunks []string
+	var current []string
+	currentTokens := 0
+	for _, unit := range units {
+		unitTokens := EstimateTokens(unit) + 1
+		if currentTokens+unitTokens > maxTokens && len(current) > 0 {
+			chunks = append(chunks, strings.Join(current, "\n"))
+			current, currentTokens = overlapTail(current, overlapTokens, maxTokens-unitTokens)
+		}
+		current = append(current, unit)
+		currentTokens += unitTokens
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, strings.Join(current, "\n"))
+	}
+	return chunks
+}
+
+// overlapTail returns the trailing units of a finished chunk that fit in the
+// overlap, without leaving less than room tokens for the next unit.
+func overlapTail(units []string, overlapTokens int, room int) ([]string, int) {
+	limit := overlapTokens
+	if room < limit {
+		limit = room
+	}
+	tokens := 0
+	start := len(units)
+	for start > 0 {
+		unitTokens := EstimateTokens(units[start-1]) + 1
+		if tokens+unitTokens > limit {
+			break
+		}
+		tokens += unitTokens
+		start--
+	}
+	return append([]string(nil), units[start:]...), tokens
+}
+
+// splitLongParagraph splits a paragraph that alone exceeds maxTokens at
+// sentence ends, falling back to hard cuts for run-on text.
+func splitLongParagraph(paragraph string, maxTokens int) []string {
+	maxChars := maxTokens * charsPerToken
+	var parts []string
+	var current strings.Builder
+	for _, sentence := range splitSentences(paragraph) {
+		if utf8.RuneCountInString(current.String())+utf8.RuneCountInString(sentence) > maxChars && current.Len() > 0 {
+			parts = append(parts, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+		for utf8.RuneCountInString(sentence) > maxChars {
+			runes := []rune(sentence)
+			parts = append(parts, string(runes[:maxChars]))
+			sentence = string(runes[maxChars:])
+		}
+		current.WriteString(sentence)
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		parts = append(parts, strings.TrimSpace(current.String()))
+	}
+	return parts
+}
+
+func splitSentences(text string) []string {
+	var sentences []string
+	start := 0
+	for i, r := range text {
+		if (r == '.' || r == '!' || r == '?') && i+1 < len(text) && text[i+1] == ' ' {
+			sentences = append(sentences, text[start:i+2])
+			start = i + 2
+		}
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+	return sentences
+}
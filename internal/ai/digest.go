@@ -0,0 +1,209 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// digestItemMaxRunes bounds how much of each article's summary is sent to
+// the model when composing a digest.
+const digestItemMaxRunes = 500
+
+// DigestItem is an article collected for a digest.
+type DigestItem struct {
+	Title   string
+	Link    string
+	Summary string
+	Topic   string
+	Source  string
+}
+
+// DigestRequest lists the collected articles. When TargetLanguage is set,
+// the digest is written in that language.
+type DigestRequest struct {
+	Items          []DigestItem
+	TargetLanguage string
+}
+
+// Digest is a roundup of articles grouped into sections, most important
+// first. Provider and Model name the model that wrote it.
+type Digest struct {
+	Intro    string
+	Sections []DigestSection
+	Provider string
+	Model    string
+}
+
+type DigestSection struct {
+	Title   string
+	Entries []DigestEntry
+}
+
+// DigestEntry is an article kept in the digest with a condensed summary.
+type DigestEntry struct {
+	Item    DigestItem
+	Summary string
+}
+
+// Digester condenses collected articles into a digest.
+type Digester interface {
+	Digest(ctx context.Context, req DigestRequest) (*Digest, error)
+}
+
+const digestInstructions = "You are writing a news digest for a Telegram channel. Below are numbered articles, each with its title, topic and summary. " +
+	"Rank them by importance, leave out duplicates and minor items, and group the rest into a few sections with short titles. " +
+	"For every article you keep, write one or two sentences. Begin with an introduction of one or two sentences.\n\n" +
+	"Respond with a JSON object with these fields: \"intro\" (the introduction) and \"sections\" (a list of objects with \"title\" and " +
+	"\"items\", a list of objects with \"article\" (the number of the article) and \"summary\"). List sections and items in order of importance."
+
+var digestSchema = &Schema{
+	Type: SchemaObject,
+	Properties: map[string]*Schema{
+		"intro": {Type: SchemaString},
+		"sections": {Type: SchemaArray, Items: &Schema{
+			Type: SchemaObject,
+			Properties: map[string]*Schema{
+				"title": {Type: SchemaString},
+				"items": {Type: SchemaArray, Items: &Schema{
+					Type: SchemaObject,
+					Properties: map[string]*Schema{
+						"article": {Type: SchemaString},
+						"summary": {Type: SchemaString},
+					},
+					Required: []string{"article", "summary"},
+				}},
+			},
+			Required: []string{"title", "items"},
+		}},
+	},
+	Required: []string{"intro", "sections"},
+}
+
+type digestJSON struct {
+	Intro    string `json:"intro"`
+	Sections []struct {
+		Title string `json:"title"`
+		Items []struct {
+			Article json.RawMessage `json:"article"`
+			Summary string          `json:"summary"`
+		} `json:"items"`
+	} `json:"sections"`
+}
+
+func (s *llmSummarizer) Digest(ctx context.Context, req DigestRequest) (*Digest, error) {
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("no articles to put in the digest")
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString(digestInstructions)
+	if req.TargetLanguage != "" {
+		prompt.WriteString(fmt.Sprintf("\nWrite the digest in %s, whatever the language of the articles.", req.TargetLanguage))
+	}
+	prompt.WriteString("\n\nArticles:\n")
+	items := req.Items
+	for i, item := range req.Items {
+		entry := fmt.Sprintf("\n%d. %s (%s)\n%s\n", i+1, item.Title, item.Topic, truncateRunes(item.Summary, digestItemMaxRunes))
+		if EstimateTokens(prompt.String()+entry) > s.inputBudget-promptReserveTokens {
+			log.Printf("Digest input exceeds the token budget of %s model %s, leaving out %d of %d articles", s.generator.Provider(), s.generator.Model(), len(req.Items)-i, len(req.Items))
+			items = req.Items[:i]
+			break
+		}
+		prompt.WriteString(entry)
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("not a single article fits into the token budget")
+	}
+
+	resp, err := s.generateComplete(ctx, Request{Prompt: prompt.String(), Schema: digestSchema})
+	if err != nil {
+		return nil, err
+	}
+	digest, err := parseDigest(resp.Text, items)
+	if err != nil {
+		return nil, err
+	}
+	digest.Provider, digest.Model = s.generator.Provider(), s.generator.Model()
+	return digest, nil
+}
+
+// parseDigest validates a structured digest and resolves the article numbers
+// the model referred to. Unknown and repeated numbers are skipped.
+func parseDigest(text string, items []DigestItem) (*Digest, error) {
+	text = stripCodeFence(text)
+
+	var raw digestJSON
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+
+	digest := &Digest{Intro: strings.TrimSpace(raw.Intro)}
+	used := make(map[int]bool)
+	for _, rawSection := range raw.Sections {
+		section := DigestSection{Title: strings.TrimSpace(rawSection.Title)}
+		for _, rawItem := range rawSection.Items {
+			number, err := strconv.Atoi(strings.Trim(string(rawItem.Article), `" `))
+			if err != nil || number < 1 || number > len(items) || used[number] {
+				continue
+			}
+			used[number] = true
+			section.Entries = append(section.Entries, DigestEntry{Item: items[number-1], Summary: strings.TrimSpace(rawItem.Summary)})
+		}
+		if len(section.Entries) > 0 {
+			digest.Sections = append(digest.Sections, section)
+		}
+	}
+	if len(digest.Sections) == 0 {
+		return nil, errors.New("response does not refer to any of the articles")
+	}
+	return digest, nil
+}
+
+// Digest asks the models of the chain in turn and, when none of them can
+// write the digest, lists the articles by topic without AI.
+func (f *fallbackSummarizer) Digest(ctx context.Context, req DigestRequest) (*Digest, error) {
+	digest, err := firstInChain(ctx, f, "write the digest", nil, func(digester Digester) (*Digest, error) {
+		return digester.Digest(ctx, req)
+	})
+	if err == nil && digest != nil {
+		return digest, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if len(req.Items) == 0 {
+		return nil, fmt.Errorf("no articles to put in the digest")
+	}
+	if err != nil {
+		log.Printf("No AI model could write the digest, listing the articles instead: %v", err)
+	}
+	return ExtractiveDigest(req.Items), nil
+}
+
+// ExtractiveDigest groups the articles by topic in the order they arrived.
+func ExtractiveDigest(items []DigestItem) *Digest {
+	digest := &Digest{Provider: ProviderExtractive}
+	sections := make(map[string]int)
+	for _, item := range items {
+		index, ok := sections[item.Topic]
+		if !ok {
+			index = len(digest.Sections)
+			sections[item.Topic] = index
+			digest.Sections = append(digest.Sections, DigestSection{Title: item.Topic})
+		}
+		digest.Sections[index].Entries = append(digest.Sections[index].Entries, DigestEntry{Item: item})
+	}
+	return digest
+}
+
+func truncateRunes(text string, limit int) string {
+	if runes := []rune(text); len(runes) > limit {
+		return strings.TrimSpace(string(runes[:limit])) + "…"
+	}
+	return text
+}
@@ -0,0 +1,165 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// ErrorKind tells callers how to react to a failed AI call.
+type ErrorKind int
+
+const (
+	// ErrorPermanent covers bad requests, auth failures and unknown models.
+	// Retrying will not help.
+	ErrorPermanent ErrorKind = iota
+	// ErrorTransient covers network trouble and server-side failures.
+	ErrorTransient
+	// ErrorRateLimited means too many requests were sent in a short time.
+	ErrorRateLimited
+	// ErrorQuotaExhausted means the account's quota is used up until it resets.
+	ErrorQuotaExhausted
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorTransient:
+		return "transient"
+	case ErrorRateLimited:
+		return "rate limited"
+	case ErrorQuotaExhausted:
+		return "quota exhausted"
+	}
+	return "permanent"
+}
+
+// StatusError is returned when a provider answers with an error status.
+type StatusError struct {
+	URL            string
+	StatusCode     int
+	Message        string
+	RetryAfter     time.Duration
+	QuotaExhausted bool
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request to %s failed with status %d: %s", e.URL, e.StatusCode, e.Message)
+}
+
+// newStatusError builds a StatusError from a failed HTTP reply.
+func newStatusError(url string, resp *http.Response, body string) *StatusError {
+	return &StatusError{
+		URL:        url,
+		StatusCode: resp.StatusCode,
+		Message:    body,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		// OpenAI reports an empty balance as a 429 with this error code.
+		QuotaExhausted: strings.Contains(body, "insufficient_quota"),
+	}
+}
+
+// parseRetryAfter reads a Retry-After header in either of its two forms.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// Classify reports what kind of failure err is and how long the provider
+// asked to wait before the next attempt, if it said so.
+func Classify(err error) (ErrorKind, time.Duration) {
+	var openErr *CircuitOpenError
+	if errors.As(err, &openErr) {
+		return ErrorRateLimited, time.Until(openErr.Until)
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		if statusErr.StatusCode == http.StatusTooManyRequests && statusErr.QuotaExhausted {
+			return ErrorQuotaExhausted, statusErr.RetryAfter
+		}
+		return classifyStatus(statusErr.StatusCode), statusErr.RetryAfter
+	}
+
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		retryAfter := parseRetryAfter(googleErr.Header.Get("Retry-After"))
+		if delay := geminiRetryDelay(googleErr); delay > 0 {
+			retryAfter = delay
+		}
+		if googleErr.Code == http.StatusTooManyRequests && geminiDailyQuotaExceeded(googleErr) {
+			return ErrorQuotaExhausted, retryAfter
+		}
+		return classifyStatus(googleErr.Code), retryAfter
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorTransient, 0
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return ErrorTransient, 0
+	}
+	return ErrorPermanent, 0
+}
+
+func classifyStatus(code int) ErrorKind {
+	switch {
+	case code == http.StatusTooManyRequests:
+		return ErrorRateLimited
+	case code == http.StatusRequestTimeout, code >= 500:
+		return ErrorTransient
+	}
+	return ErrorPermanent
+}
+
+// geminiRetryDelay reads the delay from a RetryInfo error detail.
+func geminiRetryDelay(err *googleapi.Error) time.Duration {
+	for _, detail := range err.Details {
+		fields, ok := detail.(map[string]interface{})
+		if !ok || !strings.HasSuffix(fmt.Sprint(fields["@type"]), "RetryInfo") {
+			continue
+		}
+		if delay, ok := fields["retryDelay"].(string); ok {
+			if d, err := time.ParseDuration(delay); err == nil {
+				return d
+			}
+		}
+	}
+	return 0
+}
+
+// geminiDailyQuotaExceeded reports whether a 429 was caused by a per-day
+// quota, which unlike per-minute limits won't clear with a short pause.
+func geminiDailyQuotaExceeded(err *googleapi.Error) bool {
+	for _, detail := range err.Details {
+		fields, ok := detail.(map[string]interface{})
+		if !ok || !strings.HasSuffix(fmt.Sprint(fields["@type"]), "QuotaFailure") {
+			continue
+		}
+		violations, _ := fields["violations"].([]interface{})
+		for _, violation := range violations {
+			v, _ := violation.(map[string]interface{})
+			if strings.Contains(fmt.Sprint(v["quotaId"]), "PerDay") {
+				return true
+			}
+		}
+	}
+	return false
+}
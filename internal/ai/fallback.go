@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ProviderExtractive marks summaries built from the article's own sentences
+// because no AI model could summarize it.
+const ProviderExtractive = "extractive"
+
+const (
+	extractiveSentences = 3
+	extractiveMaxRunes  = 600
+)
+
+// fallbackSummarizer tries a chain of summarizers in order.
+type fallbackSummarizer struct {
+	chain []Summarizer
+}
+
+// NewFallbackSummarizer returns a Summarizer that tries each summarizer in
+// turn and, when all of them fail, builds an extractive summary from the first
+// sentences of the article. Blocked articles and cancellation are returned
+// right away since another model would not change the outcome. If every model
+// is rate limited, out of quota or paused by its circuit breaker the error is
+// returned too, so that the article waits for the providers to recover.
+func NewFallbackSummarizer(chain ...Summarizer) Summarizer {
+	return &fallbackSummarizer{chain: chain}
+}
+
+func (f *fallbackSummarizer) Summarize(ctx context.Context, req SummaryRequest) (*Summary, error) {
+	var lastErr error
+	allThrottled := len(f.chain) > 0
+	for i, summarizer := range f.chain {
+		summary, err := summarizer.Summarize(ctx, req)
+		if err == nil {
+			return summary, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if _, blocked := IsBlocked(err); blocked {
+			return nil, err
+		}
+		if i < len(f.chain)-1 {
+			log.Printf("Summarizer %d of %d failed, trying the next one: %v", i+1, len(f.chain), err)
+		}
+		if kind, _ := Classify(err); kind != ErrorRateLimited && kind != ErrorQuotaExhausted {
+			allThrottled = false
+		}
+		lastErr = err
+	}
+	if allThrottled {
+		return nil, lastErr
+	}
+
+	text := TruncateSummary(ExtractiveSummary(req.Text), req.Style.MaxChars)
+	if text == "" {
+		if lastErr == nil {
+			lastErr = fmt.Errorf("article text is empty, cannot summarize")
+		}
+		return nil, lastErr
+	}
+	log.Printf("No AI model could summarize '%s', using its first sentences instead: %v", req.Title, lastErr)
+	return &Summary{Text: text, Provider: ProviderExtractive}, nil
+}
+
+// firstInChain calls call with each summarizer of the chain that implements
+// T until one succeeds. Cancellation and blocked content end the search right
+// away. When every summarizer fails the last error is returned, or noneErr if
+// none of them implements T.
+func firstInChain[T any, R any](ctx context.Context, f *fallbackSummarizer, task string, noneErr error, call func(T) (R, error)) (R, error) {
+	var zero R
+	lastErr := noneErr
+	for _, summarizer := range f.chain {
+		impl, ok := summarizer.(T)
+		if !ok {
+			continue
+		}
+		result, err := call(impl)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return zero, ctx.Err()
+		}
+		if _, blocked := IsBlocked(err); blocked {
+			return zero, err
+		}
+		log.Printf("A summarizer in the chain could not %s: %v", task, err)
+		lastErr = err
+	}
+	return zero, lastErr
+}
+
+// ExtractiveSummary returns the first few sentences of text.
+func ExtractiveSummary(text string) string {
+	paragraph := strings.Join(strings.Fields(text), " ")
+	var builder strings.Builder
+	for i, sentence := range splitSentences(paragraph) {
+		if i == extractiveSentences || (builder.Len() > 0 && len([]rune(builder.String()+sentence)) > extractiveMaxRunes) {
+			break
+		}
+		builder.WriteString(sentence)
+	}
+	summary := strings.TrimSpace(builder.String())
+	if runes := []rune(summary); len(runes) > extractiveMaxRunes {
+		summary = strings.TrimSpace(string(runes[:extractiveMaxRunes])) + "…"
+	}
+	return summary
+}
@@ -0,0 +1,192 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+func init() {
+	Register(ProviderGemini, newGeminiGenerator)
+	RegisterLister(ProviderGemini, listGeminiModels)
+}
+
+type geminiGenerator struct {
+	client    *genai.Client
+	modelName string
+}
+
+func newGeminiGenerator(ctx context.Context, cfg ProviderConfig, model string) (Generator, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("GEMINI_API_KEY is not set")
+	}
+	client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.APIKey))
+	if err != nil {
+		return nil, err
+	}
+	return &geminiGenerator{client: client, modelName: model}, nil
+}
+
+func (g *geminiGenerator) Close() error {
+	return g.client.Close()
+}
+
+// listGeminiModels returns the models that support generateContent.
+func listGeminiModels(ctx context.Context, cfg ProviderConfig) ([]Model, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("GEMINI_API_KEY is not set")
+	}
+	client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.APIKey))
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	var models []Model
+	it := client.ListModels(ctx)
+	for {
+		info, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, method := range info.SupportedGenerationMethods {
+			if method == "generateContent" {
+				models = append(models, Model{ID: strings.TrimPrefix(info.Name, "models/"), DisplayName: info.DisplayName})
+				break
+			}
+		}
+	}
+	return models, nil
+}
+
+func (g *geminiGenerator) Provider() string { return ProviderGemini }
+func (g *geminiGenerator) Model() string    { return g.modelName }
+
+func (g *geminiGenerator) Generate(ctx context.Context, req Request) (*Response, error) {
+	model := g.client.GenerativeModel(g.modelName)
+	if req.MaxOutputTokens > 0 {
+		model.SetMaxOutputTokens(req.MaxOutputTokens)
+	}
+	if req.Schema != nil {
+		model.ResponseMIMEType = "application/json"
+		model.ResponseSchema = geminiSchema(req.Schema)
+	}
+
+	resp, err := model.GenerateContent(ctx, genai.Text(req.Prompt))
+	if err != nil {
+		var blocked *genai.BlockedError
+		if errors.As(err, &blocked) {
+			return nil, geminiBlockedError(blocked)
+		}
+		return nil, err
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return nil, fmt.Errorf("received an empty response from AI")
+	}
+
+	candidate := resp.Candidates[0]
+	var text strings.Builder
+	for _, part := range candidate.Content.Parts {
+		if t, ok := part.(genai.Text); ok {
+			text.WriteString(string(t))
+		}
+	}
+
+	result := &Response{
+		Text:         text.String(),
+		FinishReason: geminiFinishReason(candidate.FinishReason),
+	}
+	if resp.UsageMetadata != nil {
+		result.InputTokens = int(resp.UsageMetadata.PromptTokenCount)
+		result.OutputTokens = int(resp.UsageMetadata.CandidatesTokenCount)
+	}
+	return result, nil
+}
+
+func geminiFinishReason(reason genai.FinishReason) string {
+	switch reason {
+	case genai.FinishReasonStop, genai.FinishReasonUnspecified:
+		return FinishReasonStop
+	case genai.FinishReasonMaxTokens:
+		return FinishReasonLength
+	case genai.FinishReasonSafety, genai.FinishReasonRecitation:
+		return FinishReasonSafety
+	}
+	return FinishReasonOther
+}
+
+// geminiBlockedError converts the client's error for a blocked prompt or
+// candidate, keeping the reason and safety ratings.
+func geminiBlockedError(err *genai.BlockedError) *BlockedError {
+	if err.Candidate != nil {
+		return &BlockedError{
+			Reason:  geminiEnumName(err.Candidate.FinishReason.String(), "FinishReason"),
+			Ratings: geminiSafetyRatings(err.Candidate.SafetyRatings),
+		}
+	}
+	blocked := &BlockedError{Reason: "PROMPT"}
+	if err.PromptFeedback != nil {
+		blocked.Reason = "PROMPT_" + geminiEnumName(err.PromptFeedback.BlockReason.String(), "BlockReason")
+		blocked.Ratings = geminiSafetyRatings(err.PromptFeedback.SafetyRatings)
+	}
+	return blocked
+}
+
+func geminiSafetyRatings(ratings []*genai.SafetyRating) []SafetyRating {
+	var out []SafetyRating
+	for _, rating := range ratings {
+		out = append(out, SafetyRating{
+			Category:    geminiEnumName(rating.Category.String(), "HarmCategory"),
+			Probability: geminiEnumName(rating.Probability.String(), "HarmProbability"),
+			Blocked:     rating.Blocked,
+		})
+	}
+	return out
+}
+
+// geminiEnumName turns a client enum name such as "HarmCategoryDangerousContent"
+// into the API's form, "DANGEROUS_CONTENT".
+func geminiEnumName(name string, prefix string) string {
+	var out strings.Builder
+	for i, r := range strings.TrimPrefix(name, prefix) {
+		if i > 0 && unicode.IsUpper(r) {
+			out.WriteByte('_')
+		}
+		out.WriteRune(unicode.ToUpper(r))
+	}
+	return out.String()
+}
+
+func geminiSchema(s *Schema) *genai.Schema {
+	out := &genai.Schema{Description: s.Description, Enum: s.Enum, Required: s.Required}
+	switch s.Type {
+	case SchemaObject:
+		out.Type = genai.TypeObject
+	case SchemaArray:
+		out.Type = genai.TypeArray
+	default:
+		out.Type = genai.TypeString
+	}
+	if len(s.Enum) > 0 {
+		out.Format = "enum"
+	}
+	if s.Items != nil {
+		out.Items = geminiSchema(s.Items)
+	}
+	if len(s.Properties) > 0 {
+		out.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, property := range s.Properties {
+			out.Properties[name] = geminiSchema(property)
+		}
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Model is an entry of a provider's model catalogue.
+type Model struct {
+	ID          string
+	DisplayName string
+}
+
+// Lister returns the text generation models a provider offers.
+type Lister func(ctx context.Context, cfg ProviderConfig) ([]Model, error)
+
+var listers = make(map[string]Lister)
+
+// RegisterLister makes the model catalogue of a provider available.
+func RegisterLister(name string, lister Lister) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	listers[name] = lister
+}
+
+// ModelCatalog caches the model listings of providers, which change rarely
+// and are slow to fetch.
+type ModelCatalog struct {
+	ttl     time.Duration
+	mutex   sync.Mutex
+	entries map[string]catalogEntry
+}
+
+type catalogEntry struct {
+	models    []Model
+	fetchedAt time.Time
+}
+
+func NewModelCatalog(ttl time.Duration) *ModelCatalog {
+	return &ModelCatalog{ttl: ttl, entries: make(map[string]catalogEntry)}
+}
+
+// Models returns the provider's text generation models sorted by ID, from
+// the cache if it is fresh enough. Listings are cached per account, since
+// different API keys may see different models.
+func (c *ModelCatalog) Models(ctx context.Context, provider string, cfg ProviderConfig, account string) ([]Model, error) {
+	key := provider + "/" + account
+	c.mutex.Lock()
+	entry, ok := c.entries[key]
+	c.mutex.Unlock()
+	if ok && time.Since(entry.fetchedAt) < c.ttl {
+		return entry.models, nil
+	}
+
+	models, err := ListModels(ctx, provider, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	c.entries[key] = catalogEntry{models: models, fetchedAt: time.Now()}
+	c.mutex.Unlock()
+	return models, nil
+}
+
+// ListModels fetches the provider's text generation models sorted by ID,
+// bypassing any cache.
+func ListModels(ctx context.Context, provider string, cfg ProviderConfig) ([]Model, error) {
+	registryMutex.RLock()
+	lister, ok := listers[provider]
+	registryMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("AI provider '%s' cannot list its models", provider)
+	}
+	models, err := lister(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].ID < models[j].ID })
+	return models, nil
+}
+
+// TestModel makes a tiny request to check that the model exists and the
+// provider accepts requests for it.
+func TestModel(ctx context.Context, provider string, cfg ProviderConfig, model string) error {
+	generator, err := NewGenerator(ctx, provider, cfg, model)
+	if err != nil {
+		return err
+	}
+	defer closeGenerator(generator)
+	_, err = generator.Generate(ctx, Request{Prompt: "Reply with the single word OK.", MaxOutputTokens: 16})
+	return err
+}
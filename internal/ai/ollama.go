@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const defaultOllamaBaseURL = "http://localhost:11434"
+
+func init() {
+	Register(ProviderOllama, newOllamaGenerator)
+	RegisterLister(ProviderOllama, listOllamaModels)
+}
+
+type ollamaGenerator struct {
+	baseURL   string
+	modelName string
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []openAIMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  map[string]int  `json:"options,omitempty"`
+	Format   interface{}     `json:"format,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message         openAIMessage `json:"message"`
+	DoneReason      string        `json:"done_reason"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+}
+
+// listOllamaModels returns the models pulled on the Ollama server, leaving
+// out embedding models.
+func listOllamaModels(ctx context.Context, cfg ProviderConfig) ([]Model, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOllamaBaseURL
+	}
+	var resp struct {
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := doJSON(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/api/tags", "", nil, &resp); err != nil {
+		return nil, err
+	}
+	var models []Model
+	for _, m := range resp.Models {
+		if strings.Contains(strings.ToLower(m.Name), "embed") {
+			continue
+		}
+		models = append(models, Model{ID: m.Name, DisplayName: m.Name})
+	}
+	return models, nil
+}
+
+func newOllamaGenerator(ctx context.Context, cfg ProviderConfig, model string) (Generator, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOllamaBaseURL
+	}
+	return &ollamaGenerator{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		modelName: model,
+	}, nil
+}
+
+func (g *ollamaGenerator) Provider() string { return ProviderOllama }
+func (g *ollamaGenerator) Model() string    { return g.modelName }
+
+func (g *ollamaGenerator) Generate(ctx context.Context, req Request) (*Response, error) {
+	body := ollamaChatRequest{
+		Model:    g.modelName,
+		Messages: []openAIMessage{{Role: "user", Content: req.Prompt}},
+	}
+	if req.MaxOutputTokens > 0 {
+		body.Options = map[string]int{"num_predict": int(req.MaxOutputTokens)}
+	}
+	if req.Schema != nil {
+		body.Format = req.Schema.jsonSchema()
+	}
+
+	var resp ollamaChatResponse
+	if err := postJSON(ctx, g.baseURL+"/api/chat", "", body, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Message.Content == "" {
+		return nil, fmt.Errorf("received an empty response from AI")
+	}
+	return &Response{
+		Text:         resp.Message.Content,
+		FinishReason: openAIFinishReason(resp.DoneReason),
+		InputTokens:  resp.PromptEvalCount,
+		OutputTokens: resp.EvalCount,
+	}, nil
+}
@@ -0,0 +1,195 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultOpenAIBaseURL = "https://api.openai.com/v1"
+
+// httpClient is shared by the providers that talk plain HTTP.
+var httpClient = &http.Client{Timeout: 3 * time.Minute}
+
+func init() {
+	Register(ProviderOpenAI, newOpenAIGenerator)
+	RegisterLister(ProviderOpenAI, listOpenAIModels)
+}
+
+// nonChatModelMarkers identify models in an OpenAI listing that can't be used
+// for chat completions.
+var nonChatModelMarkers = []string{"embed", "whisper", "tts", "dall-e", "moderation", "audio", "realtime", "transcribe", "image", "search", "davinci", "babbage"}
+
+// openAIGenerator talks to any OpenAI-compatible chat completions endpoint,
+// which also covers local servers such as llama.cpp and vLLM.
+type openAIGenerator struct {
+	baseURL   string
+	apiKey    string
+	modelName string
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChatRequest struct {
+	Model          string                 `json:"model"`
+	Messages       []openAIMessage        `json:"messages"`
+	MaxTokens      int32                  `json:"max_tokens,omitempty"`
+	ResponseFormat map[string]interface{} `json:"response_format,omitempty"`
+}
+
+type openAIChatResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		FinishReason string        `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func newOpenAIGenerator(ctx context.Context, cfg ProviderConfig, model string) (Generator, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	return &openAIGenerator{
+		baseURL:   strings.TrimSuffix(baseURL, "/"),
+		apiKey:    cfg.APIKey,
+		modelName: model,
+	}, nil
+}
+
+func (g *openAIGenerator) Provider() string { return ProviderOpenAI }
+func (g *openAIGenerator) Model() string    { return g.modelName }
+
+func (g *openAIGenerator) Generate(ctx context.Context, req Request) (*Response, error) {
+	body := openAIChatRequest{
+		Model:     g.modelName,
+		Messages:  []openAIMessage{{Role: "user", Content: req.Prompt}},
+		MaxTokens: req.MaxOutputTokens,
+	}
+	if req.Schema != nil {
+		body.ResponseFormat = map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   "response",
+				"schema": req.Schema.jsonSchema(),
+			},
+		}
+	}
+	var resp openAIChatResponse
+	if err := postJSON(ctx, g.baseURL+"/chat/completions", g.apiKey, body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("received an empty response from AI")
+	}
+	if resp.Choices[0].FinishReason == "content_filter" {
+		return nil, &BlockedError{Reason: "CONTENT_FILTER"}
+	}
+	return &Response{
+		Text:         resp.Choices[0].Message.Content,
+		FinishReason: openAIFinishReason(resp.Choices[0].FinishReason),
+		InputTokens:  resp.Usage.PromptTokens,
+		OutputTokens: resp.Usage.CompletionTokens,
+	}, nil
+}
+
+// listOpenAIModels returns the chat models of an OpenAI-compatible server.
+// The listing doesn't say what a model can do, so known non-chat model
+// families are filtered out by name.
+func listOpenAIModels(ctx context.Context, cfg ProviderConfig) ([]Model, error) {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	var resp struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := doJSON(ctx, http.MethodGet, strings.TrimSuffix(baseURL, "/")+"/models", cfg.APIKey, nil, &resp); err != nil {
+		return nil, err
+	}
+	var models []Model
+	for _, m := range resp.Data {
+		if !isChatModel(m.ID) {
+			continue
+		}
+		models = append(models, Model{ID: m.ID, DisplayName: m.ID})
+	}
+	return models, nil
+}
+
+func isChatModel(id string) bool {
+	lower := strings.ToLower(id)
+	for _, marker := range nonChatModelMarkers {
+		if strings.Contains(lower, marker) {
+			return false
+		}
+	}
+	return true
+}
+
+// openAIFinishReason normalizes the finish reasons of OpenAI-compatible and
+// Ollama servers.
+func openAIFinishReason(reason string) string {
+	switch reason {
+	case "stop", "":
+		return FinishReasonStop
+	case "length":
+		return FinishReasonLength
+	case "content_filter":
+		return FinishReasonSafety
+	}
+	return FinishReasonOther
+}
+
+// postJSON sends body as JSON and decodes the JSON reply into out.
+func postJSON(ctx context.Context, url string, apiKey string, body interface{}, out interface{}) error {
+	return doJSON(ctx, http.MethodPost, url, apiKey, body, out)
+}
+
+// doJSON sends a request with an optional JSON body and decodes the JSON
+// reply into out.
+func doJSON(ctx context.Context, method string, url string, apiKey string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(httpResp.Body, 1024))
+		return newStatusError(url, httpResp, strings.TrimSpace(string(detail)))
+	}
+	return json.NewDecoder(httpResp.Body).Decode(out)
+}
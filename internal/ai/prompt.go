@@ -0,0 +1,81 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PromptVariables are the article details a prompt template can reference.
+type PromptVariables struct {
+	Title       string
+	Description string
+	Link        string
+	SourceName  string
+	TopicName   string
+	PublishDate string
+	Lang        string
+}
+
+// textPlaceholder marks where the article text goes. Templates without it get
+// the text appended after the instructions.
+const textPlaceholder = "{text}"
+
+var promptPlaceholders = []string{"{title}", "{description}", "{link}", "{source_name}", "{topic_name}", "{publish_date}", "{lang}", textPlaceholder}
+
+var placeholderPattern = regexp.MustCompile(`\{[a-z_]+\}`)
+
+// ValidatePromptTemplate checks a prompt template before it is saved.
+func ValidatePromptTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("the prompt is empty")
+	}
+	for _, placeholder := range placeholderPattern.FindAllString(template, -1) {
+		known := false
+		for _, candidate := range promptPlaceholders {
+			if placeholder == candidate {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown placeholder %s", placeholder)
+		}
+	}
+	if strings.Count(template, textPlaceholder) > 1 {
+		return fmt.Errorf("%s may only be used once", textPlaceholder)
+	}
+	return nil
+}
+
+// textMarker stands in for {text} until the article text is inserted. Feed
+// data cannot produce it because renderPrompt drops NUL characters from the
+// variables.
+const textMarker = "\x00text\x00"
+
+// renderPrompt fills in every placeholder in one pass, so that a title or
+// description containing "{text}" is not taken for the placeholder. {text}
+// becomes textMarker for insertText.
+func renderPrompt(template string, vars PromptVariables) string {
+	clean := func(value string) string {
+		return strings.ReplaceAll(value, "\x00", "")
+	}
+	return strings.NewReplacer(
+		"{title}", clean(vars.Title),
+		"{description}", clean(vars.Description),
+		"{link}", clean(vars.Link),
+		"{source_name}", clean(vars.SourceName),
+		"{topic_name}", clean(vars.TopicName),
+		"{publish_date}", clean(vars.PublishDate),
+		"{lang}", clean(vars.Lang),
+		textPlaceholder, textMarker,
+	).Replace(strings.ReplaceAll(template, "\x00", ""))
+}
+
+// insertText puts content where the template has {text}, or after it.
+func insertText(prompt string, content string) string {
+	if strings.Contains(prompt, textMarker) {
+		return strings.Replace(prompt, textMarker, content, 1)
+	}
+	return fmt.Sprintf("%s \n\n%s", prompt, content)
+}
@@ -0,0 +1,24 @@
+package ai
+
+import "testing"
+
+func TestRenderPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		vars     PromptVariables
+		want     string
+	}{
+		{"text placeholder", "Summarize {title}: {text}", PromptVariables{Title: "News"}, "Summarize News: BODY"},
+		{"text placeholder in the title", "Summarize {title}: {text}", PromptVariables{Title: "About {text}"}, "Summarize About {text}: BODY"},
+		{"text placeholder in the description without one in the template", "Summarize {description}", PromptVariables{Description: "Use {text} here"}, "Summarize Use {text} here \n\nBODY"},
+		{"marker in the feed data", "{title} {text}", PromptVariables{Title: "\x00text\x00"}, "text BODY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := insertText(renderPrompt(tt.template, tt.vars), "BODY"); got != tt.want {
+				t.Errorf("prompt = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+const (
+	ProviderGemini = "gemini"
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+)
+
+// Finish reasons reported in Response, normalized across providers.
+const (
+	FinishReasonStop   = "stop"
+	FinishReasonLength = "length"
+	FinishReasonSafety = "safety"
+	FinishReasonOther  = "other"
+)
+
+// Request is a single prompt sent to a model. When Schema is set, the model
+// is asked to answer with JSON matching it.
+type Request struct {
+	Prompt          string
+	MaxOutputTokens int32
+	Schema          *Schema
+}
+
+// Schema describes the JSON a model is asked to return. It covers the subset
+// of JSON Schema that every provider understands.
+type Schema struct {
+	Type        string
+	Description string
+	Properties  map[string]*Schema
+	Required    []string
+	Items       *Schema
+	Enum        []string
+}
+
+const (
+	SchemaObject = "object"
+	SchemaArray  = "array"
+	SchemaString = "string"
+)
+
+// jsonSchema renders the schema in standard JSON Schema form.
+func (s *Schema) jsonSchema() map[string]interface{} {
+	out := map[string]interface{}{"type": s.Type}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		out["enum"] = s.Enum
+	}
+	if s.Items != nil {
+		out["items"] = s.Items.jsonSchema()
+	}
+	if len(s.Properties) > 0 {
+		properties := make(map[string]interface{}, len(s.Properties))
+		for name, property := range s.Properties {
+			properties[name] = property.jsonSchema()
+		}
+		out["properties"] = properties
+	}
+	if len(s.Required) > 0 {
+		out["required"] = s.Required
+	}
+	return out
+}
+
+// Response is the text a model generated for a Request.
+type Response struct {
+	Text         string
+	FinishReason string
+	InputTokens  int
+	OutputTokens int
+}
+
+// Generator is a text generation backend bound to one model.
+type Generator interface {
+	Generate(ctx context.Context, req Request) (*Response, error)
+	Provider() string
+	Model() string
+}
+
+// ProviderConfig holds the connection settings of a provider. OwnKey marks
+// an API key supplied by a chat rather than the operator; its failures are
+// tracked by a circuit breaker of its own.
+type ProviderConfig struct {
+	APIKey  string
+	BaseURL string
+	OwnKey  bool
+}
+
+// Factory creates a Generator for the given model.
+type Factory func(ctx context.Context, cfg ProviderConfig, model string) (Generator, error)
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]Factory)
+)
+
+// Register makes a provider available under the given name.
+func Register(name string, factory Factory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[name] = factory
+}
+
+// Providers returns the names of all registered providers, sorted.
+func Providers() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// closeGenerator releases the client held by a generator whose provider keeps
+// one open, such as Gemini.
+func closeGenerator(g Generator) error {
+	if closer, ok := g.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// NewGenerator creates a Generator using a registered provider. Calls made
+// through it are retried on transient failures, share the provider's circuit
+// breaker and report their token usage to the context's UsageRecorder.
+func NewGenerator(ctx context.Context, provider string, cfg ProviderConfig, model string) (Generator, error) {
+	registryMutex.RLock()
+	factory, ok := registry[provider]
+	registryMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown AI provider '%s'", provider)
+	}
+	if model == "" {
+		return nil, fmt.Errorf("no model configured for AI provider '%s'", provider)
+	}
+	generator, err := factory(ctx, cfg, model)
+	if err != nil {
+		return nil, err
+	}
+	return withUsage(withResilience(generator, cfg)), nil
+}
@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// QuestionRequest is a reader's question about an article.
+type QuestionRequest struct {
+	Title       string
+	ArticleText string
+	Question    string
+}
+
+// Answer is the model's reply to a question. Related is false when the
+// question has nothing to do with the article, in which case Text is empty.
+type Answer struct {
+	Text     string
+	Related  bool
+	Provider string
+	Model    string
+}
+
+// Answerer answers questions about an article using only its text.
+type Answerer interface {
+	Answer(ctx context.Context, req QuestionRequest) (*Answer, error)
+}
+
+const questionInstructions = "You answer readers' questions about a news article in a Telegram discussion. Use only the article below. " +
+	"If the question is not about the article or its subject, do not answer it. If the article does not contain the answer, say so briefly. " +
+	"Keep the answer under 120 words and write it in the language of the question.\n\n" +
+	"Respond with a JSON object with these fields: \"related\" (\"yes\" if the question is about the article, otherwise \"no\") and \"answer\"."
+
+var answerSchema = &Schema{
+	Type: SchemaObject,
+	Properties: map[string]*Schema{
+		"related": {Type: SchemaString, Enum: []string{"yes", "no"}},
+		"answer":  {Type: SchemaString},
+	},
+	Required: []string{"related", "answer"},
+}
+
+func (s *llmSummarizer) Answer(ctx context.Context, req QuestionRequest) (*Answer, error) {
+	if strings.TrimSpace(req.Question) == "" {
+		return nil, fmt.Errorf("question is empty")
+	}
+
+	header := fmt.Sprintf("%s\n\nQuestion: %s\n\nArticle title: %s\n\nArticle:\n", questionInstructions, req.Question, req.Title)
+	room := s.inputBudget - promptReserveTokens - EstimateTokens(header)
+	if room <= 0 {
+		return nil, fmt.Errorf("the question does not fit into the token budget")
+	}
+	text := req.ArticleText
+	if EstimateTokens(text) > room {
+		log.Printf("Article '%s' exceeds the token budget of %s model %s, answering from its beginning", req.Title, s.generator.Provider(), s.generator.Model())
+		text = truncateRunes(text, room*charsPerToken)
+	}
+
+	resp, err := s.generateComplete(ctx, Request{Prompt: header + text, Schema: answerSchema})
+	if err != nil {
+		return nil, err
+	}
+	answer, err := parseAnswer(resp.Text)
+	if err != nil {
+		return nil, err
+	}
+	answer.Provider, answer.Model = s.generator.Provider(), s.generator.Model()
+	return answer, nil
+}
+
+func parseAnswer(text string) (*Answer, error) {
+	text = stripCodeFence(text)
+
+	var raw struct {
+		Related string `json:"related"`
+		Answer  string `json:"answer"`
+	}
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	if !strings.EqualFold(strings.TrimSpace(raw.Related), "yes") {
+		return &Answer{}, nil
+	}
+	answer := &Answer{Text: strings.TrimSpace(raw.Answer), Related: true}
+	if answer.Text == "" {
+		return nil, errors.New("response has no answer")
+	}
+	return answer, nil
+}
+
+// Answer asks the models of the chain in turn. Unlike summaries there is no
+// extractive last resort, so the last error is returned when all fail.
+func (f *fallbackSummarizer) Answer(ctx context.Context, req QuestionRequest) (*Answer, error) {
+	return firstInChain(ctx, f, "answer the question", errors.New("no model can answer questions"), func(answerer Answerer) (*Answer, error) {
+		return answerer.Answer(ctx, req)
+	})
+}
@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// relevanceLeadRunes bounds the lead sent along with the title.
+const relevanceLeadRunes = 800
+
+// RelevanceRequest asks how well an article suits a chat's audience,
+// described by its interest profile.
+type RelevanceRequest struct {
+	Title   string
+	Lead    string
+	Profile string
+}
+
+// Relevance is a score from 0 (irrelevant) to 100 (exactly what the audience
+// wants) with a short reason.
+type Relevance struct {
+	Score    int
+	Reason   string
+	Provider string
+	Model    string
+}
+
+// RelevanceScorer rates articles against an interest profile.
+type RelevanceScorer interface {
+	ScoreRelevance(ctx context.Context, req RelevanceRequest) (*Relevance, error)
+}
+
+const relevanceInstructions = "You decide whether a news article is worth posting to a Telegram channel. The channel's audience is described below. " +
+	"Rate how relevant the article is to this audience from 0 (not at all) to 100 (exactly what they want), judging only by its title and lead.\n\n" +
+	"Respond with a JSON object with these fields: \"score\" (a whole number from 0 to 100) and \"reason\" (one short sentence)."
+
+var relevanceSchema = &Schema{
+	Type: SchemaObject,
+	Properties: map[string]*Schema{
+		"score":  {Type: SchemaString},
+		"reason": {Type: SchemaString},
+	},
+	Required: []string{"score", "reason"},
+}
+
+func (s *llmSummarizer) ScoreRelevance(ctx context.Context, req RelevanceRequest) (*Relevance, error) {
+	if strings.TrimSpace(req.Profile) == "" {
+		return nil, fmt.Errorf("interest profile is empty")
+	}
+	prompt := fmt.Sprintf("%s\n\nAudience:\n%s\n\nTitle: %s\n\nLead:\n%s", relevanceInstructions, req.Profile, req.Title, truncateRunes(req.Lead, relevanceLeadRunes))
+	resp, err := s.generateComplete(ctx, Request{Prompt: prompt, Schema: relevanceSchema})
+	if err != nil {
+		return nil, err
+	}
+	relevance, err := parseRelevance(resp.Text)
+	if err != nil {
+		return nil, err
+	}
+	relevance.Provider, relevance.Model = s.generator.Provider(), s.generator.Model()
+	return relevance, nil
+}
+
+func parseRelevance(text string) (*Relevance, error) {
+	text = stripCodeFence(text)
+
+	var raw struct {
+		Score  json.RawMessage `json:"score"`
+		Reason string          `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	score, err := strconv.ParseFloat(strings.Trim(string(raw.Score), `" `), 64)
+	if err != nil {
+		return nil, errors.New("response has no valid score")
+	}
+	relevance := &Relevance{Score: int(score + 0.5), Reason: strings.TrimSpace(raw.Reason)}
+	if relevance.Score < 0 {
+		relevance.Score = 0
+	}
+	if relevance.Score > 100 {
+		relevance.Score = 100
+	}
+	return relevance, nil
+}
+
+// ScoreRelevance rates the article with the first model of the chain that
+// can.
+func (f *fallbackSummarizer) ScoreRelevance(ctx context.Context, req RelevanceRequest) (*Relevance, error) {
+	return firstInChain(ctx, f, "rate the article", errors.New("no model can rate articles"), func(scorer RelevanceScorer) (*Relevance, error) {
+		return scorer.ScoreRelevance(ctx, req)
+	})
+}
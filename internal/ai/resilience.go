@@ -0,0 +1,232 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	maxAttempts    = 4
+	baseRetryDelay = 2 * time.Second
+	// maxRetryDelay is the longest pause spent waiting inside one call. A
+	// provider asking for more than this trips the breaker instead.
+	maxRetryDelay = time.Minute
+
+	// breakerThreshold is the number of consecutive failed calls after which
+	// a provider is paused.
+	breakerThreshold = 5
+	breakerCooldown  = 5 * time.Minute
+	quotaCooldown    = time.Hour
+	// probeWindow is how long other calls wait for the trial call made when
+	// a pause ends.
+	probeWindow = 2 * time.Minute
+)
+
+// CircuitOpenError is returned without contacting the provider while its
+// circuit breaker is open.
+type CircuitOpenError struct {
+	Provider string
+	Until    time.Time
+	Cause    error
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("AI provider %s is paused until %s: %v", e.Provider, e.Until.Format(time.RFC3339), e.Cause)
+}
+
+func (e *CircuitOpenError) Unwrap() error { return e.Cause }
+
+// breaker pauses all calls to a provider after it runs out of quota or keeps
+// failing. Once the pause is over a single trial call decides whether the
+// provider is healthy again; other calls stay paused while it runs.
+type breaker struct {
+	mutex     sync.Mutex
+	provider  string
+	ownKey    bool
+	failures  int
+	openUntil time.Time
+	halfOpen  bool
+	probing   bool
+	cause     error
+}
+
+var (
+	breakersMutex sync.Mutex
+	breakers      = make(map[string]*breaker)
+	onCircuitOpen func(provider string, until time.Time, cause error)
+)
+
+// breakerFor returns the circuit breaker of a provider. Chats using their own
+// API key get a breaker per key, so that one exhausted key doesn't pause the
+// provider for everybody else.
+func breakerFor(provider string, cfg ProviderConfig) *breaker {
+	name := provider
+	if cfg.OwnKey {
+		sum := sha256.Sum256([]byte(cfg.APIKey))
+		name += "#" + hex.EncodeToString(sum[:4])
+	}
+	breakersMutex.Lock()
+	defer breakersMutex.Unlock()
+	b, ok := breakers[name]
+	if !ok {
+		b = &breaker{provider: provider, ownKey: cfg.OwnKey}
+		breakers[name] = b
+	}
+	return b
+}
+
+// OnCircuitOpen registers a function called whenever a provider is paused.
+// Pauses of keys supplied by chats are not reported.
+func OnCircuitOpen(fn func(provider string, until time.Time, cause error)) {
+	breakersMutex.Lock()
+	defer breakersMutex.Unlock()
+	onCircuitOpen = fn
+}
+
+// PausedUntil reports whether calls to the provider with the given settings
+// are currently paused and until when.
+func PausedUntil(provider string, cfg ProviderConfig) (time.Time, bool) {
+	b := breakerFor(provider, cfg)
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if time.Now().Before(b.openUntil) {
+		return b.openUntil, true
+	}
+	return time.Time{}, false
+}
+
+func (b *breaker) allow() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := time.Now()
+	if now.Before(b.openUntil) {
+		return &CircuitOpenError{Provider: b.provider, Until: b.openUntil, Cause: b.cause}
+	}
+	if b.halfOpen {
+		// This call is the trial; keep the others out until it reports back.
+		b.probing = true
+		b.openUntil = now.Add(probeWindow)
+	}
+	return nil
+}
+
+func (b *breaker) recordSuccess() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.failures = 0
+	b.halfOpen, b.probing = false, false
+	b.openUntil = time.Time{}
+}
+
+// release lets the next call make the trial when the trial call ended
+// without telling whether the provider is healthy.
+func (b *breaker) release() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.probing {
+		b.probing = false
+		b.openUntil = time.Time{}
+	}
+}
+
+func (b *breaker) recordFailure(kind ErrorKind, retryAfter time.Duration, err error) {
+	b.mutex.Lock()
+	var pause time.Duration
+	switch kind {
+	case ErrorQuotaExhausted:
+		pause = quotaCooldown
+	case ErrorRateLimited, ErrorTransient:
+		b.failures++
+		if b.halfOpen || b.failures >= breakerThreshold || retryAfter > maxRetryDelay {
+			pause = breakerCooldown
+		}
+	}
+	if pause == 0 {
+		probing := b.probing
+		b.mutex.Unlock()
+		if probing {
+			// The provider answered, so it is reachable again.
+			b.recordSuccess()
+		}
+		return
+	}
+	if retryAfter > pause {
+		pause = retryAfter
+	}
+	until := time.Now().Add(pause)
+	b.openUntil, b.cause = until, err
+	b.failures, b.halfOpen, b.probing = 0, true, false
+	b.mutex.Unlock()
+
+	log.Printf("Pausing AI provider %s until %s: %v", b.provider, until.Format(time.RFC3339), err)
+	breakersMutex.Lock()
+	notify := onCircuitOpen
+	breakersMutex.Unlock()
+	if notify != nil && !b.ownKey {
+		go notify(b.provider, until, err)
+	}
+}
+
+// resilientGenerator retries failed calls with jittered exponential backoff
+// and reports their outcome to the provider's circuit breaker.
+type resilientGenerator struct {
+	Generator
+	breaker *breaker
+}
+
+func withResilience(g Generator, cfg ProviderConfig) Generator {
+	return &resilientGenerator{Generator: g, breaker: breakerFor(g.Provider(), cfg)}
+}
+
+func (g *resilientGenerator) Close() error { return closeGenerator(g.Generator) }
+
+func (g *resilientGenerator) Generate(ctx context.Context, req Request) (*Response, error) {
+	for attempt := 1; ; attempt++ {
+		if err := g.breaker.allow(); err != nil {
+			return nil, err
+		}
+		resp, err := g.Generator.Generate(ctx, req)
+		if err == nil {
+			g.breaker.recordSuccess()
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			g.breaker.release()
+			return nil, err
+		}
+
+		kind, retryAfter := Classify(err)
+		g.breaker.recordFailure(kind, retryAfter, err)
+		if kind == ErrorPermanent || kind == ErrorQuotaExhausted || attempt >= maxAttempts || retryAfter > maxRetryDelay {
+			return nil, err
+		}
+
+		delay := retryDelay(attempt, retryAfter)
+		log.Printf("AI request to %s model %s failed (%s, attempt %d of %d), retrying in %s: %v", g.Provider(), g.Model(), kind, attempt, maxAttempts, delay.Round(time.Second), err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// retryDelay doubles the pause with every attempt and adds up to 50% jitter
+// so that chats hitting the same limit don't retry in lockstep. A delay
+// requested by the provider is always respected.
+func retryDelay(attempt int, retryAfter time.Duration) time.Duration {
+	delay := baseRetryDelay << (attempt - 1)
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
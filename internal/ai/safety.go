@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SafetyRating is a provider's assessment of one harm category.
+type SafetyRating struct {
+	Category    string
+	Probability string
+	Blocked     bool
+}
+
+func (r SafetyRating) String() string {
+	return fmt.Sprintf("%s: %s", r.Category, r.Probability)
+}
+
+// BlockedError is returned when the provider refused to answer, for example
+// for SAFETY or RECITATION. Retrying the same prompt will not help.
+type BlockedError struct {
+	Reason  string
+	Ratings []SafetyRating
+}
+
+func (e *BlockedError) Error() string {
+	if flagged := e.FlaggedRatings(); len(flagged) > 0 {
+		var parts []string
+		for _, rating := range flagged {
+			parts = append(parts, rating.String())
+		}
+		return fmt.Sprintf("response blocked by the model: %s (%s)", e.Reason, strings.Join(parts, ", "))
+	}
+	return fmt.Sprintf("response blocked by the model: %s", e.Reason)
+}
+
+// FlaggedRatings returns the ratings that caused the block or were rated
+// above a low probability.
+func (e *BlockedError) FlaggedRatings() []SafetyRating {
+	var flagged []SafetyRating
+	for _, rating := range e.Ratings {
+		if rating.Blocked || rating.Probability == "MEDIUM" || rating.Probability == "HIGH" {
+			flagged = append(flagged, rating)
+		}
+	}
+	return flagged
+}
+
+// IsBlocked reports whether err is or wraps a BlockedError.
+func IsBlocked(err error) (*BlockedError, bool) {
+	var blocked *BlockedError
+	if errors.As(err, &blocked) {
+		return blocked, true
+	}
+	return nil, false
+}
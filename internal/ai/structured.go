@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	SentimentPositive = "positive"
+	SentimentNeutral  = "neutral"
+	SentimentNegative = "negative"
+)
+
+// Summary is the result of summarizing an article. Only Text is guaranteed to
+// be set; the other fields are empty when the model's structured output could
+// not be used and the summary fell back to plain text. Provider and Model
+// name the model that produced it. Unsupported lists the claims a
+// verification pass found no support for in the article.
+type Summary struct {
+	Text            string
+	Headline        string
+	KeyPoints       []string
+	Hashtags        []string
+	Sentiment       string
+	Category        string
+	TitleTranslated string
+	Provider        string
+	Model           string
+	Unsupported     []string
+}
+
+// ModelName returns "provider/model", or just the provider for extractive
+// summaries.
+func (s *Summary) ModelName() string {
+	if s.Model == "" {
+		return s.Provider
+	}
+	return s.Provider + "/" + s.Model
+}
+
+// structuredInstructions are appended to the summary prompt when requesting
+// the summarySchema response.
+const structuredInstructions = "\n\nRespond with a JSON object with these fields: " +
+	"\"summary\" (the summary, written exactly as instructed above), " +
+	"\"headline\" (a concise rewritten headline), " +
+	"\"key_points\" (3 to 5 short key points), " +
+	"\"hashtags\" (3 to 5 relevant hashtags), " +
+	"\"sentiment\" (positive, neutral or negative) and " +
+	"\"category\" (the news category in one or two words)."
+
+var summarySchema = &Schema{
+	Type: SchemaObject,
+	Properties: map[string]*Schema{
+		"summary":    {Type: SchemaString},
+		"headline":   {Type: SchemaString},
+		"key_points": {Type: SchemaArray, Items: &Schema{Type: SchemaString}},
+		"hashtags":   {Type: SchemaArray, Items: &Schema{Type: SchemaString}},
+		"sentiment":  {Type: SchemaString, Enum: []string{SentimentPositive, SentimentNeutral, SentimentNegative}},
+		"category":   {Type: SchemaString},
+	},
+	Required: []string{"summary", "headline", "key_points", "hashtags", "sentiment", "category"},
+}
+
+// summaryWithTitleSchema extends summarySchema with the translated title.
+var summaryWithTitleSchema = func() *Schema {
+	schema := *summarySchema
+	schema.Properties = map[string]*Schema{"title_translated": {Type: SchemaString}}
+	for name, property := range summarySchema.Properties {
+		schema.Properties[name] = property
+	}
+	schema.Required = append(append([]string(nil), summarySchema.Required...), "title_translated")
+	return &schema
+}()
+
+func titleTranslationInstructions(req SummaryRequest) string {
+	return structuredInstructions + fmt.Sprintf(" Also include \"title_translated\": the original title %q translated into %s.", req.Title, req.TargetLanguage)
+}
+
+type summaryJSON struct {
+	Summary   string   `json:"summary"`
+	Headline  string   `json:"headline"`
+	KeyPoints []string `json:"key_points"`
+	Hashtags  []string `json:"hashtags"`
+	Sentiment string   `json:"sentiment"`
+	Category  string   `json:"category"`
+	Title     string   `json:"title_translated"`
+}
+
+// stripCodeFence removes the Markdown code fence some models wrap JSON in.
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	return strings.TrimSpace(text)
+}
+
+// parseSummary validates a structured response against summarySchema, or
+// summaryWithTitleSchema when withTitle is set.
+func parseSummary(text string, withTitle bool) (*Summary, error) {
+	text = stripCodeFence(text)
+
+	var raw summaryJSON
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	if strings.TrimSpace(raw.Summary) == "" {
+		return nil, fmt.Errorf("response has no summary")
+	}
+	if withTitle && strings.TrimSpace(raw.Title) == "" {
+		return nil, fmt.Errorf("response has no translated title")
+	}
+
+	summary := &Summary{
+		Text:            strings.TrimSpace(raw.Summary),
+		Headline:        strings.TrimSpace(raw.Headline),
+		Category:        strings.TrimSpace(raw.Category),
+		TitleTranslated: strings.TrimSpace(raw.Title),
+	}
+	for _, point := range raw.KeyPoints {
+		if point = strings.TrimSpace(strings.TrimLeft(point, "-•* ")); point != "" {
+			summary.KeyPoints = append(summary.KeyPoints, point)
+		}
+	}
+	for _, tag := range raw.Hashtags {
+		if tag = normalizeHashtag(tag); tag != "" {
+			summary.Hashtags = append(summary.Hashtags, tag)
+		}
+	}
+	switch sentiment := strings.ToLower(strings.TrimSpace(raw.Sentiment)); sentiment {
+	case SentimentPositive, SentimentNeutral, SentimentNegative:
+		summary.Sentiment = sentiment
+	}
+	return summary, nil
+}
+
+// normalizeHashtag turns "#Climate change" or "climate-change" into "#ClimateChange".
+func normalizeHashtag(tag string) string {
+	var builder strings.Builder
+	upperNext := false
+	for _, r := range strings.TrimLeft(strings.TrimSpace(tag), "#") {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			if upperNext {
+				r = unicode.ToUpper(r)
+			}
+			builder.WriteRune(r)
+			upperNext = false
+		default:
+			upperNext = builder.Len() > 0
+		}
+	}
+	if builder.Len() == 0 {
+		return ""
+	}
+	return "#" + builder.String()
+}
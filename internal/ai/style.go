@@ -0,0 +1,119 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxShortenAttempts is how often a summary over the length limit is sent
+// back to the model before it is cut.
+const maxShortenAttempts = 2
+
+// SummaryStyle controls the shape of a summary. MaxChars is the most
+// characters the summary may have, counted like TextLength, or 0 for no
+// limit. Format and Emoji take
+// the values of the chat's summary_format and summary_emoji settings; other
+// values leave the matter to the prompt.
+type SummaryStyle struct {
+	MaxChars int
+	Format   string
+	Emoji    string
+}
+
+var formatInstructions = map[string]string{
+	"paragraph": "Write the summary as a single paragraph of prose, without lists or headings.",
+	"bullets":   "Write the summary as a list of short bullet points, one per line, each starting with \"• \".",
+	"thread":    "Write the summary as a short thread of numbered posts (1/, 2/, 3/ and so on), each one or two sentences long and separated by a blank line.",
+}
+
+var emojiInstructions = map[string]string{
+	"none": "Do not use any emoji.",
+	"few":  "Use at most one or two fitting emoji.",
+	"many": "Use fitting emoji generously to make the summary lively.",
+}
+
+// instructions returns the style as sentences to append to the prompt.
+func (style SummaryStyle) instructions() string {
+	var parts []string
+	if style.MaxChars > 0 {
+		parts = append(parts, fmt.Sprintf("Keep the summary under %d characters.", style.MaxChars))
+	}
+	if instruction, ok := formatInstructions[style.Format]; ok {
+		parts = append(parts, instruction)
+	}
+	if instruction, ok := emojiInstructions[style.Emoji]; ok {
+		parts = append(parts, instruction)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "\n" + strings.Join(parts, " ")
+}
+
+// enforceLength sends a summary over the length limit back to the model to be
+// shortened and cuts it if it is still too long.
+func (s *llmSummarizer) enforceLength(ctx context.Context, summary *Summary, style SummaryStyle) {
+	if style.MaxChars <= 0 {
+		return
+	}
+	for attempt := 0; attempt < maxShortenAttempts; attempt++ {
+		length := TextLength(summary.Text)
+		if length <= style.MaxChars {
+			return
+		}
+		log.Printf("Summary from %s model %s has %d characters, over the limit of %d, asking for a shorter one", s.generator.Provider(), s.generator.Model(), length, style.MaxChars)
+		prompt := fmt.Sprintf("This summary is %d characters long but must not exceed %d characters. Rewrite it in at most %d characters, keeping the most important facts, its language and its format.%s Reply with the rewritten summary only.\n\n%s",
+			length, style.MaxChars, style.MaxChars*9/10, style.instructions(), summary.Text)
+		shorter, err := s.generate(ctx, prompt)
+		if err != nil {
+			log.Printf("Could not shorten the summary with %s model %s: %v", s.generator.Provider(), s.generator.Model(), err)
+			break
+		}
+		summary.Text = shorter
+	}
+	summary.Text = TruncateSummary(summary.Text, style.MaxChars)
+}
+
+// TextLength counts the characters of text the way Telegram does, in UTF-16
+// code units, so that an emoji or other character outside the Basic
+// Multilingual Plane counts as two.
+func TextLength(text string) int {
+	length := 0
+	for _, r := range text {
+		length += utf16.RuneLen(r)
+	}
+	return length
+}
+
+// TruncateSummary cuts text to at most maxChars characters, counted like
+// TextLength, preferably after the last whole sentence or line, and marks a
+// cut mid-sentence with an ellipsis.
+func TruncateSummary(text string, maxChars int) string {
+	if maxChars <= 0 || TextLength(text) <= maxChars {
+		return text
+	}
+	cut, length := text, 0
+	for i, r := range text {
+		if length+utf16.RuneLen(r) > maxChars-1 {
+			cut = text[:i]
+			break
+		}
+		length += utf16.RuneLen(r)
+	}
+	end := strings.LastIndex(cut, "\n")
+	for _, mark := range []string{". ", "! ", "? "} {
+		if index := strings.LastIndex(cut, mark); index+1 > end {
+			end = index + 1
+		}
+	}
+	if end > len(cut)/2 {
+		return strings.TrimSpace(cut[:end])
+	}
+	if space := strings.LastIndex(cut, " "); space > len(cut)/2 {
+		cut = cut[:space]
+	}
+	return strings.TrimSpace(cut) + "…"
+}
@@ -0,0 +1,51 @@
+package ai
+
+import "testing"
+
+func TestTextLength(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{"empty", "", 0},
+		{"ASCII", "abc", 3},
+		{"accented letter", "café", 4},
+		{"emoji counts as two", "😀", 2},
+		{"mixed", "a😀b", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TextLength(tt.text); got != tt.want {
+				t.Errorf("TextLength(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxChars int
+		want     string
+	}{
+		{"fits", "Short text.", 50, "Short text."},
+		{"no limit", "Short text.", 0, "Short text."},
+		{"after the last sentence", "First sentence here. Second sentence is much longer than that.", 30, "First sentence here."},
+		{"after the last line", "Line one is here\nLine two goes on and on", 25, "Line one is here"},
+		{"at a word with an ellipsis", "Words without any sentence break in this text", 20, "Words without any…"},
+		{"not inside an emoji", "😀😀😀😀😀😀", 5, "😀😀…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TruncateSummary(tt.text, tt.maxChars)
+			if got != tt.want {
+				t.Errorf("TruncateSummary(%q, %d) = %q, want %q", tt.text, tt.maxChars, got, tt.want)
+			}
+			if tt.maxChars > 0 && TextLength(got) > tt.maxChars {
+				t.Errorf("TruncateSummary(%q, %d) is %d characters long", tt.text, tt.maxChars, TextLength(got))
+			}
+		})
+	}
+}
@@ -3,49 +3,222 @@ package ai
 import (
 	"context"
 	"fmt"
-
-	"github.com/google/generative-ai-go/genai"
-	"google.golang.org/api/option"
+	"log"
+	"strings"
 )
 
-type Summarizer struct {
-	model        *genai.GenerativeModel
+// DefaultInputTokenBudget is used when no budget is configured for a model.
+const DefaultInputTokenBudget = 30000
+
+// promptReserveTokens leaves room for instructions wrapped around the text.
+const promptReserveTokens = 300
+
+// maxReduceRounds bounds how often partial summaries are summarized again.
+const maxReduceRounds = 3
+
+// shortenedWordLimits are the answer lengths asked for, in turn, when an
+// answer is cut off by the output token limit.
+var shortenedWordLimits = []int{150, 80}
+
+// Summarizer turns article text into a summary for a Telegram post.
+type Summarizer interface {
+	Summarize(ctx context.Context, req SummaryRequest) (*Summary, error)
+}
+
+// SummaryRequest is the article to summarize. When TargetLanguage is set, the
+// summary is written in that language regardless of the article's own
+// language, and with TranslateTitle the original title is translated too.
+// URL is the canonical article link used as the cache key. Style sets the
+// summary's length, format and emoji use.
+type SummaryRequest struct {
+	Text           string
+	Title          string
+	URL            string
+	TargetLanguage string
+	TranslateTitle bool
+	Variables      PromptVariables
+	Style          SummaryStyle
+}
+
+type llmSummarizer struct {
+	generator    Generator
 	promptFormat string
+	inputBudget  int
+	cache        SummaryCache
+}
+
+// NewSummarizer returns a Summarizer that prompts the given generator with
+// promptFormat, a template validated by ValidatePromptTemplate. Texts
+// longer than inputBudget tokens are summarized in overlapping chunks whose
+// partial summaries are merged in a final pass. If cache is not nil, requests
+// with a URL are looked up there first.
+func NewSummarizer(generator Generator, promptFormat string, inputBudget int, cache SummaryCache) Summarizer {
+	if inputBudget <= 0 {
+		inputBudget = DefaultInputTokenBudget
+	}
+	return &llmSummarizer{
+		generator:    generator,
+		promptFormat: promptFormat,
+		inputBudget:  inputBudget,
+		cache:        cache,
+	}
 }
 
-func NewSummarizer(ctx context.Context, apiKey string, modelName string, promptFormat string) (*Summarizer, error) {
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+func (s *llmSummarizer) Summarize(ctx context.Context, req SummaryRequest) (*Summary, error) {
+	if req.Text == "" {
+		return nil, fmt.Errorf("article text is empty, cannot summarize")
+	}
+	if req.TargetLanguage == "" {
+		req.TranslateTitle = false
+	}
+
+	prompt := renderPrompt(s.promptFormat, req.Variables)
+	if req.TargetLanguage != "" {
+		prompt = fmt.Sprintf("%s\nWrite the summary and every other field in %s, whatever the language of the article.", prompt, req.TargetLanguage)
+	}
+	prompt += req.Style.instructions()
+
+	if s.cache == nil || req.URL == "" {
+		return s.summarize(ctx, prompt, req)
+	}
+	key := s.cacheKey(prompt, req)
+	if summary, ok := s.cache.GetSummary(key); ok {
+		recordUsage(ctx, Usage{Provider: key.Provider, Model: key.Model, CacheHit: true})
+		summary.Provider, summary.Model = key.Provider, key.Model
+		return summary, nil
+	}
+	summary, err := s.summarize(ctx, prompt, req)
 	if err != nil {
 		return nil, err
 	}
+	s.cache.PutSummary(key, summary)
+	return summary, nil
+}
 
-	model := client.GenerativeModel(modelName)
-	return &Summarizer{
-		model:        model,
-		promptFormat: promptFormat,
-	}, nil
+func (s *llmSummarizer) summarize(ctx context.Context, prompt string, req SummaryRequest) (*Summary, error) {
+	summary, err := s.summarizeText(ctx, prompt, req)
+	if err != nil {
+		return nil, err
+	}
+	s.enforceLength(ctx, summary, req.Style)
+	summary.Provider, summary.Model = s.generator.Provider(), s.generator.Model()
+	return summary, nil
+}
+
+func (s *llmSummarizer) summarizeText(ctx context.Context, prompt string, req SummaryRequest) (*Summary, error) {
+	textBudget := s.textBudget(prompt)
+	if EstimateTokens(req.Text) <= textBudget {
+		return s.finalPass(ctx, insertText(prompt, fmt.Sprintf("\"%s\"", req.Text)), req)
+	}
+
+	partials, err := s.summarizeChunks(ctx, req.Text, textBudget)
+	if err != nil {
+		return nil, err
+	}
+	content := fmt.Sprintf("The article was too long to send at once, so below are summaries of its consecutive parts. Treat them as one article:\n\n%s", strings.Join(partials, "\n\n"))
+	return s.finalPass(ctx, insertText(prompt, content), req)
 }
 
-func (s *Summarizer) Summarize(ctx context.Context, articleText string) (string, error) {
-	if articleText == "" {
-		return "", fmt.Errorf("article text is empty, cannot summarize")
+// finalPass requests the structured summary and falls back to a plain-text
+// summary when the provider rejects the schema or its answer does not match it.
+func (s *llmSummarizer) finalPass(ctx context.Context, prompt string, req SummaryRequest) (*Summary, error) {
+	instructions, schema := structuredInstructions, summarySchema
+	if req.TranslateTitle {
+		instructions, schema = titleTranslationInstructions(req), summaryWithTitleSchema
+	}
+	resp, err := s.generateComplete(ctx, Request{Prompt: prompt + instructions, Schema: schema})
+	if err == nil {
+		summary, parseErr := parseSummary(resp.Text, req.TranslateTitle)
+		if parseErr == nil {
+			return summary, nil
+		}
+		err = parseErr
 	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if _, blocked := IsBlocked(err); blocked {
+		return nil, err
+	}
+	if kind, _ := Classify(err); kind != ErrorPermanent {
+		return nil, err
+	}
+	log.Printf("Structured summary from %s model %s is unusable, falling back to plain text: %v", s.generator.Provider(), s.generator.Model(), err)
 
-	prompt := fmt.Sprintf("%s \n\n\"%s\"", s.promptFormat, articleText)
+	text, err := s.generate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	summary := &Summary{Text: text}
+	if req.TranslateTitle {
+		translated, err := s.generate(ctx, fmt.Sprintf("Translate this news headline into %s. Reply with the translation only.\n\n%s", req.TargetLanguage, req.Title))
+		if err != nil {
+			log.Printf("Could not translate title with %s model %s: %v", s.generator.Provider(), s.generator.Model(), err)
+		}
+		summary.TitleTranslated = translated
+	}
+	return summary, nil
+}
+
+// summarizeChunks is the map step. If the partial summaries together are
+// still over budget they are summarized again until they fit.
+func (s *llmSummarizer) summarizeChunks(ctx context.Context, text string, textBudget int) ([]string, error) {
+	for round := 1; ; round++ {
+		chunks := splitIntoChunks(text, textBudget, textBudget/10)
+		partials := make([]string, 0, len(chunks))
+		for i, chunk := range chunks {
+			prompt := fmt.Sprintf("Below is part %d of %d of a longer article. Summarize the facts, names, numbers and quotes it contains in a few sentences. Do not add an introduction; your summary will be combined with those of the other parts.\n\n\"%s\"", i+1, len(chunks), chunk)
+			partial, err := s.generate(ctx, prompt)
+			if err != nil {
+				return nil, fmt.Errorf("failed to summarize part %d of %d: %w", i+1, len(chunks), err)
+			}
+			partials = append(partials, fmt.Sprintf("[Part %d] %s", i+1, partial))
+		}
+
+		text = strings.Join(partials, "\n")
+		if EstimateTokens(text) <= textBudget || len(chunks) == 1 || round == maxReduceRounds {
+			return partials, nil
+		}
+	}
+}
 
-	resp, err := s.model.GenerateContent(ctx, genai.Text(prompt))
+func (s *llmSummarizer) textBudget(prompt string) int {
+	budget := s.inputBudget - EstimateTokens(prompt) - promptReserveTokens
+	if budget < promptReserveTokens {
+		budget = promptReserveTokens
+	}
+	return budget
+}
+
+func (s *llmSummarizer) generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := s.generateComplete(ctx, Request{Prompt: prompt})
 	if err != nil {
 		return "", fmt.Errorf("failed to generate content: %w", err)
 	}
 
-	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
+	summary := strings.TrimSpace(resp.Text)
+	if summary == "" {
 		return "", fmt.Errorf("received an empty response from AI")
 	}
+	return summary, nil
+}
 
-	summary, ok := resp.Candidates[0].Content.Parts[0].(genai.Text)
-	if !ok {
-		return "", fmt.Errorf("unexpected response format from AI")
+// generateComplete sends req and, when the answer was cut off by the output
+// token limit, asks again for a shorter one instead of returning it truncated.
+func (s *llmSummarizer) generateComplete(ctx context.Context, req Request) (*Response, error) {
+	prompt := req.Prompt
+	for attempt := 0; ; attempt++ {
+		resp, err := s.generator.Generate(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.FinishReason != FinishReasonLength {
+			return resp, nil
+		}
+		if attempt == len(shortenedWordLimits) {
+			return nil, fmt.Errorf("the answer of %s model %s was cut off by the output token limit", s.generator.Provider(), s.generator.Model())
+		}
+		log.Printf("Answer from %s model %s was truncated, asking again for at most %d words", s.generator.Provider(), s.generator.Model(), shortenedWordLimits[attempt])
+		req.Prompt = fmt.Sprintf("%s\n\nKeep the whole answer short: no more than %d words.", prompt, shortenedWordLimits[attempt])
 	}
-
-	return string(summary), nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TopicNone is the answer of a classifier when no topic fits the article.
+const TopicNone = "none"
+
+// classificationTextRunes bounds how much of the article is sent to the
+// model; the opening is enough to tell what an article is about.
+const classificationTextRunes = 2000
+
+// TopicRequest asks which of Topics an article belongs to.
+type TopicRequest struct {
+	Title  string
+	Text   string
+	Topics []string
+}
+
+// TopicChoice is the topic a model picked, or TopicNone, with its confidence
+// between 0 and 1.
+type TopicChoice struct {
+	Topic      string
+	Confidence float64
+	Provider   string
+	Model      string
+}
+
+// TopicClassifier picks the topic an article belongs to.
+type TopicClassifier interface {
+	ClassifyTopic(ctx context.Context, req TopicRequest) (*TopicChoice, error)
+}
+
+const topicInstructions = "You sort news articles into the topics of a Telegram channel. Pick the one topic below that fits the article best, " +
+	"or \"none\" if none of them fits.\n\n" +
+	"Respond with a JSON object with these fields: \"topic\" (the topic exactly as listed, or \"none\") and \"confidence\" " +
+	"(how sure you are, as a number between 0 and 1)."
+
+func (s *llmSummarizer) ClassifyTopic(ctx context.Context, req TopicRequest) (*TopicChoice, error) {
+	if len(req.Topics) == 0 {
+		return nil, fmt.Errorf("no topics to choose from")
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString(topicInstructions)
+	prompt.WriteString("\n\nTopics:\n")
+	for _, topic := range req.Topics {
+		prompt.WriteString("- " + topic + "\n")
+	}
+	prompt.WriteString(fmt.Sprintf("\nArticle title: %s\n\nArticle:\n%s", req.Title, truncateRunes(req.Text, classificationTextRunes)))
+
+	schema := &Schema{
+		Type: SchemaObject,
+		Properties: map[string]*Schema{
+			"topic":      {Type: SchemaString, Enum: append(append([]string(nil), req.Topics...), TopicNone)},
+			"confidence": {Type: SchemaString},
+		},
+		Required: []string{"topic", "confidence"},
+	}
+	resp, err := s.generateComplete(ctx, Request{Prompt: prompt.String(), Schema: schema})
+	if err != nil {
+		return nil, err
+	}
+	choice, err := parseTopicChoice(resp.Text, req.Topics)
+	if err != nil {
+		return nil, err
+	}
+	choice.Provider, choice.Model = s.generator.Provider(), s.generator.Model()
+	return choice, nil
+}
+
+// parseTopicChoice reads the model's pick. A topic that is not on the list
+// counts as TopicNone.
+func parseTopicChoice(text string, topics []string) (*TopicChoice, error) {
+	text = stripCodeFence(text)
+
+	var raw struct {
+		Topic      string          `json:"topic"`
+		Confidence json.RawMessage `json:"confidence"`
+	}
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	confidence, err := strconv.ParseFloat(strings.Trim(string(raw.Confidence), `" `), 64)
+	if err != nil {
+		return nil, errors.New("response has no valid confidence")
+	}
+	if confidence > 1 {
+		confidence /= 100
+	}
+	if confidence < 0 {
+		confidence = 0
+	}
+
+	choice := &TopicChoice{Topic: TopicNone, Confidence: confidence}
+	for _, topic := range topics {
+		if strings.EqualFold(strings.TrimSpace(raw.Topic), topic) {
+			choice.Topic = topic
+			break
+		}
+	}
+	return choice, nil
+}
+
+// ClassifyTopic picks a topic with the first model of the chain that can.
+func (f *fallbackSummarizer) ClassifyTopic(ctx context.Context, req TopicRequest) (*TopicChoice, error) {
+	return firstInChain(ctx, f, "classify the article", errors.New("no model can classify articles"), func(classifier TopicClassifier) (*TopicChoice, error) {
+		return classifier.ClassifyTopic(ctx, req)
+	})
+}
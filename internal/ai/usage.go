@@ -0,0 +1,57 @@
+package ai
+
+import "context"
+
+// Usage describes one AI call. For a cache hit no call was made and the
+// token counts are zero.
+type Usage struct {
+	Provider     string
+	Model        string
+	InputTokens  int
+	OutputTokens int
+	CacheHit     bool
+}
+
+// UsageRecorder receives the usage of every successful AI call and of every
+// summary served from the cache.
+type UsageRecorder func(usage Usage)
+
+type usageRecorderKey struct{}
+
+// WithUsageRecorder returns a context whose AI calls are reported to record.
+// Summarizers are shared between chats, so this is how calls are attributed
+// to the chat that made them.
+func WithUsageRecorder(ctx context.Context, record UsageRecorder) context.Context {
+	return context.WithValue(ctx, usageRecorderKey{}, record)
+}
+
+func recordUsage(ctx context.Context, usage Usage) {
+	if record, ok := ctx.Value(usageRecorderKey{}).(UsageRecorder); ok {
+		record(usage)
+	}
+}
+
+// usageGenerator reports the usage of each call to the context's recorder.
+type usageGenerator struct {
+	Generator
+}
+
+func withUsage(g Generator) Generator {
+	return &usageGenerator{Generator: g}
+}
+
+func (g *usageGenerator) Close() error { return closeGenerator(g.Generator) }
+
+func (g *usageGenerator) Generate(ctx context.Context, req Request) (*Response, error) {
+	resp, err := g.Generator.Generate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	usage := Usage{Provider: g.Provider(), Model: g.Model(), InputTokens: resp.InputTokens, OutputTokens: resp.OutputTokens}
+	// Some local servers don't report usage, so fall back to an estimate.
+	if usage.InputTokens == 0 && usage.OutputTokens == 0 {
+		usage.InputTokens, usage.OutputTokens = EstimateTokens(req.Prompt), EstimateTokens(resp.Text)
+	}
+	recordUsage(ctx, usage)
+	return resp, nil
+}
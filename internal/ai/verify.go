@@ -0,0 +1,183 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var (
+	numberPattern   = regexp.MustCompile(`\d+(?:[.,]\d+)*`)
+	namePattern     = regexp.MustCompile(`\p{Lu}[\p{L}\p{M}\d'’-]+`)
+	markdownPattern = regexp.MustCompile("[*_`#>~]+")
+)
+
+// SummaryClaims splits a summary into the statements a verification pass
+// checks: the headline, every sentence of the text and every key point.
+func SummaryClaims(summary *Summary) []string {
+	var claims []string
+	add := func(text string) {
+		text = strings.TrimSpace(markdownPattern.ReplaceAllString(text, ""))
+		text = strings.TrimLeft(text, "-•+ ")
+		if text != "" {
+			claims = append(claims, text)
+		}
+	}
+	add(summary.Headline)
+	for _, line := range strings.Split(summary.Text, "\n") {
+		for _, sentence := range splitSentences(strings.TrimSpace(line)) {
+			add(sentence)
+		}
+	}
+	for _, point := range summary.KeyPoints {
+		add(point)
+	}
+	return claims
+}
+
+// UnsupportedClaims returns the claims with a number, or with checkNames a
+// capitalized name, that does not occur in the source text. Names are only
+// worth checking when the summary is in the language of the source.
+func UnsupportedClaims(claims []string, source string, checkNames bool) []string {
+	lowerSource := strings.ToLower(source)
+	numbers := make(map[string]bool)
+	for _, number := range numberPattern.FindAllString(source, -1) {
+		numbers[normalizeNumber(number)] = true
+	}
+
+	var unsupported []string
+	for _, claim := range claims {
+		if !claimSupported(claim, lowerSource, numbers, checkNames) {
+			unsupported = append(unsupported, claim)
+		}
+	}
+	return unsupported
+}
+
+func claimSupported(claim string, lowerSource string, numbers map[string]bool, checkNames bool) bool {
+	for _, number := range numberPattern.FindAllString(claim, -1) {
+		// Single digits are often spelled out in the article.
+		if len(number) == 1 {
+			continue
+		}
+		if !numbers[normalizeNumber(number)] {
+			return false
+		}
+	}
+	if !checkNames || isTitleCase(claim) {
+		return true
+	}
+	for _, loc := range namePattern.FindAllStringIndex(claim, -1) {
+		// The first word of a sentence is capitalized anyway.
+		if strings.TrimFunc(claim[:loc[0]], func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }) == "" {
+			continue
+		}
+		if !strings.Contains(lowerSource, strings.ToLower(claim[loc[0]:loc[1]])) {
+			return false
+		}
+	}
+	return true
+}
+
+// isTitleCase reports whether most words of text are capitalized, as in
+// headlines, where capitals say nothing about names.
+func isTitleCase(text string) bool {
+	words := strings.Fields(text)
+	capitalized := 0
+	for _, word := range words {
+		if first := []rune(word)[0]; unicode.IsUpper(first) {
+			capitalized++
+		}
+	}
+	return len(words) > 2 && capitalized*2 > len(words)
+}
+
+// normalizeNumber drops thousands separators and decimal marks so that
+// "1,500", "1.500" and "1500" compare equal.
+func normalizeNumber(number string) string {
+	return strings.NewReplacer(",", "", ".", "").Replace(number)
+}
+
+// VerificationRequest asks which claims of a summary the source does not support.
+type VerificationRequest struct {
+	Claims []string
+	Source string
+}
+
+// Verifier checks the claims of a summary against the article with a model.
+type Verifier interface {
+	VerifyClaims(ctx context.Context, req VerificationRequest) ([]string, error)
+}
+
+const verificationInstructions = "You check a summary of a news article for mistakes. Below are the article and numbered statements from the summary, " +
+	"which may be in another language than the article. A statement is unsupported if it contains a fact, name or number that the article does not state.\n\n" +
+	"Respond with a JSON object with the field \"unsupported\": a list of the numbers of the unsupported statements, empty if all are supported."
+
+var verificationSchema = &Schema{
+	Type: SchemaObject,
+	Properties: map[string]*Schema{
+		"unsupported": {Type: SchemaArray, Items: &Schema{Type: SchemaString}},
+	},
+	Required: []string{"unsupported"},
+}
+
+func (s *llmSummarizer) VerifyClaims(ctx context.Context, req VerificationRequest) ([]string, error) {
+	if len(req.Claims) == 0 {
+		return nil, nil
+	}
+
+	var header strings.Builder
+	header.WriteString(verificationInstructions + "\n\nStatements:\n")
+	for i, claim := range req.Claims {
+		header.WriteString(fmt.Sprintf("%d. %s\n", i+1, claim))
+	}
+	header.WriteString("\nArticle:\n")
+	room := s.inputBudget - promptReserveTokens - EstimateTokens(header.String())
+	if room <= 0 {
+		return nil, fmt.Errorf("the summary does not fit into the token budget")
+	}
+	source := req.Source
+	if EstimateTokens(source) > room {
+		return nil, fmt.Errorf("the article exceeds the token budget of %s model %s", s.generator.Provider(), s.generator.Model())
+	}
+
+	resp, err := s.generateComplete(ctx, Request{Prompt: header.String() + source, Schema: verificationSchema})
+	if err != nil {
+		return nil, err
+	}
+	return parseVerification(resp.Text, req.Claims)
+}
+
+func parseVerification(text string, claims []string) ([]string, error) {
+	text = stripCodeFence(text)
+
+	var raw struct {
+		Unsupported []json.RawMessage `json:"unsupported"`
+	}
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return nil, fmt.Errorf("response is not valid JSON: %w", err)
+	}
+	var unsupported []string
+	used := make(map[int]bool)
+	for _, item := range raw.Unsupported {
+		number, err := strconv.Atoi(strings.Trim(string(item), `" `))
+		if err != nil || number < 1 || number > len(claims) || used[number] {
+			continue
+		}
+		used[number] = true
+		unsupported = append(unsupported, claims[number-1])
+	}
+	return unsupported, nil
+}
+
+// VerifyClaims checks the claims with the first model of the chain that can.
+func (f *fallbackSummarizer) VerifyClaims(ctx context.Context, req VerificationRequest) ([]string, error) {
+	return firstInChain(ctx, f, "verify the summary", errors.New("no model can verify summaries"), func(verifier Verifier) ([]string, error) {
+		return verifier.VerifyClaims(ctx, req)
+	})
+}
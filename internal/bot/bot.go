@@ -3,14 +3,18 @@ package bot
 import (
 	"context"
 	"fmt"
+	"html"
 	"log"
 	"news-bot/config"
 	"news-bot/internal/ai"
+	"news-bot/internal/filter"
 	"news-bot/internal/localization"
 	"news-bot/internal/news_fetcher"
 	"news-bot/internal/scheduler"
+	"news-bot/internal/secrets"
 	"news-bot/internal/storage"
 	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -20,6 +24,9 @@ type ConversationState struct {
 	PendingSource       news_fetcher.Source
 	PendingArticleID    int64
 	PendingTopicName    string
+	PendingFilter       filter.Rule
+	PendingPattern      string
+	PendingProvider     string
 	OriginalMessageID   int
 	OriginalChatID      int64
 	OriginalMessageText string
@@ -36,8 +43,14 @@ type TelegramBot struct {
 	ctx             context.Context
 	userStates      map[int64]*ConversationState
 	stateMutex      sync.Mutex
-	summarizers     map[string]*ai.Summarizer
+	summarizers     map[string]ai.Summarizer
 	summarizerMutex sync.RWMutex
+	modelCatalog    *ai.ModelCatalog
+	questionTimes   map[int64][]time.Time
+	questionMutex   sync.Mutex
+	threadRoots     map[threadMessage]threadRoot
+	threadMutex     sync.Mutex
+	keyBox          *secrets.Box
 	isFetching      map[int64]bool
 	fetchingMutex   sync.Mutex
 	cancelFunc      context.CancelFunc
@@ -66,17 +79,101 @@ func NewBot(
 		scheduler:      scheduler,
 		storage:        storage,
 		userStates:     make(map[int64]*ConversationState),
-		summarizers:    make(map[string]*ai.Summarizer),
+		summarizers:    make(map[string]ai.Summarizer),
+		modelCatalog:   ai.NewModelCatalog(modelCatalogTTL),
+		questionTimes:  make(map[int64][]time.Time),
+		threadRoots:    make(map[threadMessage]threadRoot),
 		isFetching:     make(map[int64]bool),
 		ctx:            ctx,
 	}
+	if globalCfg.KeyEncryptionSecret != "" {
+		if bot.keyBox, err = secrets.NewBox(globalCfg.KeyEncryptionSecret); err != nil {
+			return nil, fmt.Errorf("failed to set up API key encryption: %w", err)
+		}
+		fetcher.SetCredentialBox(bot.keyBox)
+	}
+	ai.OnCircuitOpen(bot.alertProviderPaused)
 
 	return bot, nil
 }
 
-func (b *TelegramBot) getSummarizerForChat(chatCfg *config.Config) (*ai.Summarizer, error) {
+// alertProviderPaused tells the superadmin that summarization with a provider
+// is paused, typically because its quota is used up.
+func (b *TelegramBot) alertProviderPaused(provider string, until time.Time, cause error) {
+	lang := b.getLangForChat(b.globalCfg.SuperAdminID)
+	text := fmt.Sprintf(b.localizer.GetMessage(lang, "ai_provider_paused_alert"), provider, until.Format("2006-01-02 15:04 MST"), html.EscapeString(cause.Error()))
+	msg := tgbotapi.NewMessage(b.globalCfg.SuperAdminID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	if _, err := b.api.Send(msg); err != nil {
+		log.Printf("Failed to alert superadmin about paused AI provider %s: %v", provider, err)
+	}
+}
+
+// providerConfig returns the connection settings of an AI provider.
+func (b *TelegramBot) providerConfig(provider string) ai.ProviderConfig {
+	switch provider {
+	case ai.ProviderGemini:
+		return ai.ProviderConfig{APIKey: b.globalCfg.GeminiAPIKey}
+	case ai.ProviderOpenAI:
+		return ai.ProviderConfig{APIKey: b.globalCfg.OpenAIAPIKey, BaseURL: b.globalCfg.OpenAIBaseURL}
+	case ai.ProviderOllama:
+		return ai.ProviderConfig{BaseURL: b.globalCfg.OllamaBaseURL}
+	}
+	return ai.ProviderConfig{}
+}
+
+// configuredProviders returns the registered providers this instance has
+// settings for, or the chat has its own API key for if chatCfg is not nil.
+func (b *TelegramBot) configuredProviders(chatCfg *config.Config) []string {
+	var providers []string
+	for _, provider := range ai.Providers() {
+		cfg := b.providerConfig(provider)
+		if chatCfg != nil {
+			cfg = b.chatProviderConfig(chatCfg, provider)
+		}
+		if cfg.APIKey != "" || cfg.BaseURL != "" {
+			providers = append(providers, provider)
+		}
+	}
+	return providers
+}
+
+func chatProvider(chatCfg *config.Config) string {
+	if chatCfg.AIProvider == "" {
+		return ai.ProviderGemini
+	}
+	return chatCfg.AIProvider
+}
+
+// getSummarizerForChat returns a summarizer that tries the chat's model, then
+// its fallback models, and finally an extractive summary. With ownKeysOnly,
+// models that would run on the operator's keys are left out.
+func (b *TelegramBot) getSummarizerForChat(chatCfg *config.Config, ownKeysOnly bool) (ai.Summarizer, error) {
+	refs := append([]modelRef{{Provider: chatProvider(chatCfg), Model: chatCfg.AIModel}}, modelFallbacks(chatCfg)...)
+	var chain []ai.Summarizer
+	for _, ref := range refs {
+		providerCfg := b.chatProviderConfig(chatCfg, ref.Provider)
+		if ownKeysOnly && !providerCfg.OwnKey {
+			continue
+		}
+		summarizer, err := b.getSummarizer(ref.Provider, providerCfg, ref.Model, chatCfg.AiPrompt)
+		if err != nil {
+			log.Printf("Skipping %s in the summarizer chain: %v", ref, err)
+			continue
+		}
+		chain = append(chain, summarizer)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no usable AI model is configured for this chat")
+	}
+	return ai.NewFallbackSummarizer(chain...), nil
+}
+
+// getSummarizer returns the shared summarizer of a model and prompt. Chats
+// using their own API key share it only with chats using the same key.
+func (b *TelegramBot) getSummarizer(provider string, providerCfg ai.ProviderConfig, model string, prompt string) (ai.Summarizer, error) {
 	b.summarizerMutex.RLock()
-	configKey := fmt.Sprintf("%s-%s", chatCfg.GeminiModel, chatCfg.AiPrompt)
+	configKey := fmt.Sprintf("%s-%s-%s-%s", provider, keyFingerprint(providerCfg), model, prompt)
 	summarizer, exists := b.summarizers[configKey]
 	b.summarizerMutex.RUnlock()
 
@@ -92,11 +189,12 @@ func (b *TelegramBot) getSummarizerForChat(chatCfg *config.Config) (*ai.Summariz
 		return summarizer, nil
 	}
 
-	log.Printf("Creating new summarizer instance for model %s", chatCfg.GeminiModel)
-	newSummarizer, err := ai.NewSummarizer(b.ctx, b.globalCfg.GeminiAPIKey, chatCfg.GeminiModel, chatCfg.AiPrompt)
+	log.Printf("Creating new summarizer instance for %s model %s", provider, model)
+	generator, err := ai.NewGenerator(b.ctx, provider, providerCfg, model)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create new summarizer instance: %w", err)
 	}
+	newSummarizer := ai.NewSummarizer(generator, prompt, b.globalCfg.InputTokenBudget(model), &storageSummaryCache{storage: b.storage})
 
 	b.summarizers[configKey] = newSummarizer
 	return newSummarizer, nil
@@ -107,6 +205,10 @@ func (b *TelegramBot) Start() {
 	log.Printf("Authorized on account %s", b.api.Self.UserName)
 
 	b.scheduleNewsDispatcher()
+	b.scheduleSummaryCacheCleanup()
+	b.scheduleDigests()
+	b.schedulePostedMessageCleanup()
+	b.sealNewsletterCredentials()
 	b.scheduler.Start()
 
 	b.listenForUpdates()
@@ -142,6 +244,8 @@ func (b *TelegramBot) listenForUpdates() {
 
 		if ok {
 			go b.handleStatefulMessage(update.Message)
+		} else if update.Message.ReplyToMessage != nil || b.mentionsBot(update.Message) {
+			go b.handleArticleQuestion(update.Message)
 		}
 	}
 }
@@ -0,0 +1,242 @@
+package bot
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"log"
+	"news-bot/config"
+	"news-bot/internal/ai"
+	"news-bot/internal/news_fetcher"
+	"news-bot/internal/storage"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatProviderConfig returns the connection settings a chat uses for a
+// provider: its own API key if it stored one, otherwise the operator's.
+func (b *TelegramBot) chatProviderConfig(chatCfg *config.Config, provider string) ai.ProviderConfig {
+	providerCfg := b.providerConfig(provider)
+	if b.keyBox == nil || chatCfg.BYOKProvider != provider || chatCfg.BYOKAPIKey == "" {
+		return providerCfg
+	}
+	key, err := b.keyBox.Open(chatCfg.BYOKAPIKey)
+	if err != nil {
+		log.Printf("Could not decrypt a chat's own %s API key: %v", provider, err)
+		return providerCfg
+	}
+	providerCfg.APIKey, providerCfg.OwnKey = key, true
+	return providerCfg
+}
+
+// keyFingerprint identifies a chat's own API key without revealing it.
+func keyFingerprint(providerCfg ai.ProviderConfig) string {
+	if !providerCfg.OwnKey {
+		return "shared"
+	}
+	sum := sha256.Sum256([]byte(providerCfg.APIKey))
+	return hex.EncodeToString(sum[:8])
+}
+
+// byokRequired reports whether the chat has to bring its own API key because
+// the superadmin limited the operator's keys to a number of free chats.
+func (b *TelegramBot) byokRequired(chatID int64) bool {
+	if chatID == b.globalCfg.SuperAdminID {
+		return false
+	}
+	value, err := b.storage.GetSetting(storage.SettingBYOKFreeChats)
+	if err != nil {
+		if err != storage.ErrNotFound {
+			log.Printf("[Chat %d] Could not read the BYOK setting: %v", chatID, err)
+		}
+		return false
+	}
+	freeChats, err := strconv.Atoi(value)
+	if err != nil {
+		return false
+	}
+	rank, err := b.storage.GetChatSignupRank(chatID)
+	if err != nil {
+		log.Printf("[Chat %d] Could not determine whether the chat is in the free tier: %v", chatID, err)
+		return false
+	}
+	return rank > freeChats
+}
+
+// validateAPIKey checks a key with a test request to the chat's model of the
+// provider, or by listing the provider's models if the chat uses none.
+func (b *TelegramBot) validateAPIKey(chatID int64, chatCfg *config.Config, providerCfg ai.ProviderConfig, provider string) error {
+	model := ""
+	if chatProvider(chatCfg) == provider {
+		model = chatCfg.AIModel
+	}
+	for _, ref := range modelFallbacks(chatCfg) {
+		if model == "" && ref.Provider == provider {
+			model = ref.Model
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(b.usageContext(b.ctx, chatID), modelTestTimeout)
+	defer cancel()
+	if model == "" {
+		_, err := ai.ListModels(ctx, provider, providerCfg)
+		return err
+	}
+	return ai.TestModel(ctx, provider, providerCfg, model)
+}
+
+// handleSetKeyCommand stores a chat's own API key: /set_key <chat_id>
+// <provider> <api_key>. It only works in a private chat with the bot, and the
+// message carrying the key is deleted right away.
+func (b *TelegramBot) handleSetKeyCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	userID := message.From.ID
+	lang := b.getLangForChat(chatID)
+	b.api.Request(tgbotapi.NewDeleteMessage(chatID, message.MessageID))
+
+	if !message.Chat.IsPrivate() {
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "set_key_private_only"))
+		return
+	}
+	if b.keyBox == nil {
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "byok_disabled"))
+		return
+	}
+	parts := strings.Fields(message.CommandArguments())
+	if len(parts) != 3 {
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "set_key_usage"))
+		return
+	}
+	targetChatID, err := strconv.ParseInt(parts[0], 10, 64)
+	provider, key := parts[1], parts[2]
+	if err != nil || !isKnownProvider(provider) || provider == ai.ProviderOllama {
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "set_key_usage"))
+		return
+	}
+	chatCfg, err := b.storage.GetChatConfig(targetChatID)
+	if err != nil {
+		b.sendModelCommandReply(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, "set_quota_unknown_chat"), targetChatID))
+		return
+	}
+	if !b.isChatAdmin(targetChatID, userID) {
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "permission_denied"))
+		return
+	}
+
+	b.api.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, "model_testing"), provider)))
+	providerCfg := b.providerConfig(provider)
+	providerCfg.APIKey, providerCfg.OwnKey = key, true
+	if err := b.validateAPIKey(targetChatID, chatCfg, providerCfg, provider); err != nil {
+		log.Printf("[Chat %d] Own %s API key sent by user %d failed validation: %v", targetChatID, provider, userID, err)
+		b.sendModelCommandReply(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, "set_key_invalid"), html.EscapeString(provider), html.EscapeString(err.Error())))
+		return
+	}
+
+	sealed, err := b.keyBox.Seal(key)
+	if err != nil {
+		log.Printf("[Chat %d] Failed to encrypt API key: %v", targetChatID, err)
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "settings_error"))
+		return
+	}
+	if err := b.storage.SetChatAPIKey(targetChatID, provider, sealed); err != nil {
+		log.Printf("Failed to save the own API key of chat %d: %v", targetChatID, err)
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "settings_error"))
+		return
+	}
+	log.Printf("[Chat %d] User %d stored an own %s API key.", targetChatID, userID, provider)
+	b.sendModelCommandReply(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, "set_key_success"), html.EscapeString(provider), targetChatID))
+}
+
+// handleRemoveKeyCommand deletes a chat's own API key: /remove_key
+// [chat_id]. Without an ID it applies to the current chat.
+func (b *TelegramBot) handleRemoveKeyCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := b.getLangForChat(chatID)
+	targetChatID := chatID
+	if arg := strings.TrimSpace(message.CommandArguments()); arg != "" {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "remove_key_usage"))
+			return
+		}
+		targetChatID = id
+	}
+	if configured, err := b.storage.IsChatConfigured(targetChatID); err != nil || !configured {
+		b.sendModelCommandReply(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, "set_quota_unknown_chat"), targetChatID))
+		return
+	}
+	if !b.isChatAdmin(targetChatID, message.From.ID) {
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "permission_denied"))
+		return
+	}
+
+	if err := b.storage.SetChatAPIKey(targetChatID, "", ""); err != nil {
+		log.Printf("Failed to remove the own API key of chat %d: %v", targetChatID, err)
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "settings_error"))
+		return
+	}
+	b.sendModelCommandReply(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, "remove_key_success"), targetChatID))
+}
+
+// handleRequireBYOKCommand limits the operator's API keys to the first
+// chats that started using the bot: /require_byok <free_chats> or
+// /require_byok off.
+func (b *TelegramBot) handleRequireBYOKCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := b.getLangForChat(chatID)
+	arg := strings.TrimSpace(message.CommandArguments())
+	if arg == "off" {
+		if err := b.storage.DeleteSetting(storage.SettingBYOKFreeChats); err != nil {
+			log.Printf("Failed to clear the BYOK setting: %v", err)
+			return
+		}
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "require_byok_off"))
+		return
+	}
+
+	freeChats, err := strconv.Atoi(arg)
+	if err != nil || freeChats < 0 {
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "require_byok_usage"))
+		return
+	}
+	if b.keyBox == nil {
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "byok_disabled"))
+		return
+	}
+	if err := b.storage.SetSetting(storage.SettingBYOKFreeChats, strconv.Itoa(freeChats)); err != nil {
+		log.Printf("Failed to save the BYOK setting: %v", err)
+		return
+	}
+	b.sendModelCommandReply(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, "require_byok_success"), freeChats))
+}
+
+// sealNewsletterCredentials encrypts IMAP passwords that newsletter sources
+// stored in plaintext before credentials were sealed.
+func (b *TelegramBot) sealNewsletterCredentials() {
+	if b.keyBox == nil {
+		return
+	}
+	sources, err := b.storage.GetAllNewsSources()
+	if err != nil {
+		log.Printf("Failed to load news sources to seal their credentials: %v", err)
+		return
+	}
+	for _, source := range sources {
+		if source.Type != "email" {
+			continue
+		}
+		sealed, err := news_fetcher.SealNewsletterURL(source.URL, b.keyBox)
+		if err != nil || sealed == source.URL {
+			continue
+		}
+		if err := b.storage.UpdateNewsSourceURL(source.ID, sealed); err != nil {
+			log.Printf("Failed to seal the credentials of source %d: %v", source.ID, err)
+			continue
+		}
+		log.Printf("Sealed the IMAP password of source %d.", source.ID)
+	}
+}
@@ -0,0 +1,153 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"news-bot/config"
+	"news-bot/internal/ai"
+	"news-bot/internal/news_fetcher"
+	"news-bot/internal/storage"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// classifyArticleTopic asks the chat's models which of its topics the article
+// belongs to and routes the article there. When the model is unsure the
+// article keeps its source's topic, or, if the chat prefers, a note for the
+// moderators is returned and the article should go to moderation.
+func (b *TelegramBot) classifyArticleTopic(ctx context.Context, chatCfg *config.Config, summarizer ai.Summarizer, topics []storage.Topic, source *news_fetcher.Source, article *news_fetcher.Article) string {
+	chatID := source.ChatID
+	classifier, ok := summarizer.(ai.TopicClassifier)
+	if !ok {
+		return ""
+	}
+
+	names := make([]string, len(topics))
+	for i, topic := range topics {
+		names[i] = topic.Name
+	}
+	text := article.TextContent
+	if text == "" {
+		text = article.Description
+	}
+
+	choice, err := classifier.ClassifyTopic(b.usageContext(ctx, chatID), ai.TopicRequest{Title: article.Title, Text: text, Topics: names})
+	if err != nil {
+		log.Printf("[Chat %d] Could not classify article '%s': %v", chatID, article.Title, err)
+		choice = &ai.TopicChoice{Topic: ai.TopicNone}
+	}
+	if choice.Topic != ai.TopicNone && choice.Confidence >= chatCfg.ClassificationThreshold {
+		for _, topic := range topics {
+			if topic.Name != choice.Topic {
+				continue
+			}
+			log.Printf("[Chat %d] Classified '%s' as topic '%s' (confidence %.2f).", chatID, article.Title, topic.Name, choice.Confidence)
+			source.TopicID = topic.ID
+			source.TopicName = topic.Name
+			source.DestinationChatID = topic.DestinationChatID
+			source.ReplyToMessageID = topic.ReplyToMessageID
+			return ""
+		}
+	}
+
+	log.Printf("[Chat %d] Unsure about the topic of '%s' (best guess '%s', confidence %.2f).", chatID, article.Title, choice.Topic, choice.Confidence)
+	if chatCfg.ClassificationFallback != config.ClassificationFallbackModeration {
+		return ""
+	}
+	lang := b.getLangForChat(chatID)
+	return fmt.Sprintf(b.localizer.GetMessage(lang, "moderation_note_unclassified"), html.EscapeString(choice.Topic), percent(choice.Confidence))
+}
+
+func percent(value float64) int {
+	return int(math.Round(value * 100))
+}
+
+func (b *TelegramBot) sendClassificationMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for classification menu for chat %d: %v", chatID, err)
+		return
+	}
+
+	status := b.localizer.GetMessage(lang, "status_off")
+	if cfg.TopicClassification {
+		status = b.localizer.GetMessage(lang, "status_on")
+	}
+	fallback := b.localizer.GetMessage(lang, "classification_fallback_"+config.ClassificationFallbackSource)
+	if cfg.ClassificationFallback == config.ClassificationFallbackModeration {
+		fallback = b.localizer.GetMessage(lang, "classification_fallback_"+config.ClassificationFallbackModeration)
+	}
+	text := fmt.Sprintf(b.localizer.GetMessage(lang, "classification_menu_title"), status, percent(cfg.ClassificationThreshold), fallback)
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(b.localizer.GetMessage(lang, "btn_toggle_classification"), status), "toggle_classification"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_edit_classification_threshold"), "edit_classification_threshold"),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(b.localizer.GetMessage(lang, "btn_toggle_classification_fallback"), fallback), "toggle_classification_fallback"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_main_settings"), "back_to_settings"),
+		),
+	)
+	b.sendOrEditMenu(chatID, messageID, text, keyboard)
+}
+
+func (b *TelegramBot) handleClassificationCallback(callback *tgbotapi.CallbackQuery, action string) {
+	userID := callback.From.ID
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	lang := b.getLangForChat(chatID)
+
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for chat %d: %v", chatID, err)
+		return
+	}
+
+	switch action {
+	case "classification_menu":
+		b.clearUserState(userID)
+	case "toggle_classification":
+		if err := b.storage.UpdateChatConfig(chatID, "topic_classification", !cfg.TopicClassification); err != nil {
+			log.Printf("Failed to update topic_classification for chat %d: %v", chatID, err)
+		}
+	case "toggle_classification_fallback":
+		newValue := config.ClassificationFallbackModeration
+		if cfg.ClassificationFallback == config.ClassificationFallbackModeration {
+			newValue = config.ClassificationFallbackSource
+		}
+		if err := b.storage.UpdateChatConfig(chatID, "classification_fallback", newValue); err != nil {
+			log.Printf("Failed to update classification_fallback for chat %d: %v", chatID, err)
+		}
+	case "edit_classification_threshold":
+		b.setUserState(userID, &ConversationState{Step: StateAwaitingTopicThreshold})
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, b.localizer.GetMessage(lang, "ask_classification_threshold"))
+		editMsg.ParseMode = tgbotapi.ModeHTML
+		b.api.Send(editMsg)
+		return
+	}
+	b.sendClassificationMenu(chatID, messageID)
+}
+
+// parseThreshold reads a confidence threshold given as "0.6", "60" or "60%".
+func parseThreshold(text string) (float64, error) {
+	value, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(text), "%"), 64)
+	if err != nil {
+		return 0, err
+	}
+	if value > 1 {
+		value /= 100
+	}
+	if value < 0 || value > 1 {
+		return 0, fmt.Errorf("threshold %v is out of range", value)
+	}
+	return value, nil
+}
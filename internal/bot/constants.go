@@ -13,6 +13,24 @@ const (
 	StateAwaitingArticleEdit      = "awaiting_article_edit"
 	StateAwaitingRSSMaxAge        = "awaiting_rss_max_age"
 	StateAwaitingTargetForward    = "awaiting_target_forward"
+	StateBuildingFilter           = "building_filter"
+	StateAwaitingFilterPattern    = "awaiting_filter_pattern"
+	StateAwaitingCategoryPattern  = "awaiting_category_pattern"
+	StateAwaitingCategoryTopic    = "awaiting_category_topic"
+	StateAwaitingSourceLanguages  = "awaiting_source_languages"
+	StateAwaitingModelName        = "awaiting_model_name"
+	StateAwaitingFallbackModel    = "awaiting_fallback_model"
+	StateAwaitingDigestSchedule   = "awaiting_digest_schedule"
+	StateAwaitingDigestWindow     = "awaiting_digest_window"
+	StateAwaitingDigestTemplate   = "awaiting_digest_template"
+	StateAwaitingTopicThreshold   = "awaiting_topic_threshold"
+	StateAwaitingInterestProfile  = "awaiting_interest_profile"
+	StateAwaitingMinRelevance     = "awaiting_min_relevance"
+	StateAwaitingPresetName       = "awaiting_preset_name"
+	StateAwaitingSummaryLength    = "awaiting_summary_length"
 	newsFetchingJobTag            = "news_fetching_job"
+	summaryCacheCleanupJobTag     = "summary_cache_cleanup_job"
+	digestJobTagPrefix            = "digest_job"
+	postedMessageCleanupJobTag    = "posted_message_cleanup_job"
 	CallbackLinkTopicDest         = "link_topic_dest"
 )
\ No newline at end of file
@@ -0,0 +1,531 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"news-bot/config"
+	"news-bot/internal/ai"
+	"news-bot/internal/langdetect"
+	"news-bot/internal/news_fetcher"
+	"news-bot/internal/scheduler"
+	"news-bot/internal/storage"
+	"news-bot/internal/telegramhtml"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// maxDigestItems bounds a digest to the newest articles of its window.
+	maxDigestItems   = 40
+	digestTimeout    = 3 * time.Minute
+	maxDigestWindow  = 31 * 24
+	weeklyDigestCron = "0 8 * * 1"
+	// telegramMessageLimit is the maximum length of a text message.
+	telegramMessageLimit = 4096
+)
+
+var extraBlankLines = regexp.MustCompile(`\n{3,}`)
+
+func digestJobTag(chatID int64) string {
+	return fmt.Sprintf("%s_%d", digestJobTagPrefix, chatID)
+}
+
+// digestTopicIDs returns the chat's topics that collect articles for a digest
+// of their own.
+func (b *TelegramBot) digestTopicIDs(chatID int64) map[int64]bool {
+	topics, err := b.storage.GetTopicsForChat(chatID)
+	if err != nil {
+		log.Printf("[Chat %d] Could not load topics for digest mode: %v", chatID, err)
+		return nil
+	}
+	ids := make(map[int64]bool)
+	for _, topic := range topics {
+		if topic.DigestMode {
+			ids[topic.ID] = true
+		}
+	}
+	return ids
+}
+
+func (b *TelegramBot) scheduleDigests() {
+	configs, err := b.storage.GetAllChatConfigs()
+	if err != nil {
+		log.Printf("Failed to load chat configs to schedule digests: %v", err)
+		return
+	}
+	for _, chatConfig := range configs {
+		b.refreshDigestSchedule(chatConfig.ChatID)
+	}
+}
+
+// refreshDigestSchedule registers the chat's digest job with its current
+// schedule, or removes it when neither the chat nor a topic uses digests.
+func (b *TelegramBot) refreshDigestSchedule(chatID int64) {
+	tag := digestJobTag(chatID)
+	b.scheduler.RemoveJobByTag(tag)
+
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("[Chat %d] Could not get config to schedule the digest: %v", chatID, err)
+		return
+	}
+	if !cfg.DigestEnabled && len(b.digestTopicIDs(chatID)) == 0 {
+		return
+	}
+	schedule := cfg.DigestSchedule
+	if schedule == "" {
+		schedule = config.DefaultDigestSchedule
+	}
+	if err := b.scheduler.AddCronJob(tag, schedule, func() { b.publishDigests(chatID, false) }); err != nil {
+		log.Printf("[Chat %d] Could not schedule the digest with '%s': %v", chatID, schedule, err)
+	}
+}
+
+// queueForDigest collects a summarized article for the next digest instead of
+// posting it.
+func (b *TelegramBot) queueForDigest(chatCfg *config.Config, article *news_fetcher.Article, summary *ai.Summary, source news_fetcher.Source) error {
+	item := storage.DigestQueueItem{
+		ChatID:    source.ChatID,
+		Title:     article.Title,
+		Link:      article.Link,
+		Summary:   telegramhtml.StripTags(summary.Text),
+		TopicName: source.TopicName,
+	}
+	if !chatCfg.DigestEnabled {
+		item.TopicID = source.TopicID
+	}
+	if sourceURL, err := url.Parse(source.URL); err == nil {
+		item.SourceName = strings.TrimPrefix(sourceURL.Hostname(), "www.")
+	}
+	return b.storage.AddDigestItem(item)
+}
+
+// publishDigests writes and publishes, or sends for approval, one digest for
+// the chat and one for each topic with a digest of its own.
+func (b *TelegramBot) publishDigests(chatID int64, manual bool) {
+	lang := b.getLangForChat(chatID)
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("[Chat %d] Could not get config for the digest: %v", chatID, err)
+		return
+	}
+
+	window := cfg.DigestWindowHours
+	if window <= 0 {
+		window = 24
+	}
+	since := time.Now().Add(-time.Duration(window) * time.Hour)
+	if err := b.storage.DeleteDigestItemsBefore(chatID, since); err != nil {
+		log.Printf("[Chat %d] Failed to drop articles outside the digest window: %v", chatID, err)
+	}
+	items, err := b.storage.GetDigestItems(chatID, since)
+	if err != nil {
+		log.Printf("[Chat %d] Could not load the digest queue: %v", chatID, err)
+		return
+	}
+	if len(items) == 0 {
+		log.Printf("[Chat %d] No articles collected for the digest.", chatID)
+		if manual {
+			b.api.Send(tgbotapi.NewMessage(chatID, b.localizer.GetMessage(lang, "digest_empty")))
+		}
+		return
+	}
+
+	var order []int64
+	groups := make(map[int64][]storage.DigestQueueItem)
+	for _, item := range items {
+		if _, ok := groups[item.TopicID]; !ok {
+			order = append(order, item.TopicID)
+		}
+		groups[item.TopicID] = append(groups[item.TopicID], item)
+	}
+
+	for _, topicID := range order {
+		group := groups[topicID]
+		text, err := b.composeDigest(chatID, cfg, lang, group)
+		if err != nil {
+			log.Printf("[Chat %d] Could not write the digest: %v", chatID, err)
+			continue
+		}
+		if cfg.DigestApproval {
+			err = b.sendDigestToModeration(chatID, topicID, cfg, text)
+		} else {
+			err = b.postDigest(chatID, topicID, text)
+		}
+		if err != nil {
+			log.Printf("[Chat %d] Failed to publish the digest, keeping its articles queued: %v", chatID, err)
+			continue
+		}
+
+		ids := make([]int64, len(group))
+		for i, item := range group {
+			ids[i] = item.ID
+		}
+		if err := b.storage.DeleteDigestItems(ids); err != nil {
+			log.Printf("[Chat %d] Failed to clear the digest queue: %v", chatID, err)
+		}
+	}
+}
+
+// composeDigest asks the chat's models to rank and condense the articles and
+// renders the result with the digest template.
+func (b *TelegramBot) composeDigest(chatID int64, cfg *config.Config, lang string, group []storage.DigestQueueItem) (string, error) {
+	if len(group) > maxDigestItems {
+		group = group[len(group)-maxDigestItems:]
+	}
+	req := ai.DigestRequest{}
+	if target := summaryLanguage(cfg); target != "" {
+		req.TargetLanguage = langdetect.Name(target)
+	}
+	for _, item := range group {
+		topic := item.TopicName
+		if topic == "" {
+			topic = "General"
+		}
+		req.Items = append(req.Items, ai.DigestItem{Title: item.Title, Link: item.Link, Summary: item.Summary, Topic: topic, Source: item.SourceName})
+	}
+
+	var digest *ai.Digest
+	if used, exceeded := b.monthlyQuotaExceeded(chatID, cfg.MonthlyTokenQuota); exceeded {
+		log.Printf("[Chat %d] Monthly AI token quota of %d reached (%d used), listing the digest articles without AI.", chatID, cfg.MonthlyTokenQuota, used)
+		digest = ai.ExtractiveDigest(req.Items)
+	} else {
+		summarizer, err := b.getSummarizerForChat(cfg, b.byokRequired(chatID))
+		if err != nil {
+			return "", err
+		}
+		digester, ok := summarizer.(ai.Digester)
+		if !ok {
+			return "", fmt.Errorf("the chat's summarizer cannot write digests")
+		}
+		ctx, cancel := context.WithTimeout(b.usageContext(b.ctx, chatID), digestTimeout)
+		defer cancel()
+		if digest, err = digester.Digest(ctx, req); err != nil {
+			return "", err
+		}
+	}
+
+	topicName := group[0].TopicName
+	if topicName == "" {
+		topicName = "General"
+	}
+	return b.renderDigest(cfg, lang, digest, topicName, len(group)), nil
+}
+
+func (b *TelegramBot) renderDigest(cfg *config.Config, lang string, digest *ai.Digest, topicName string, count int) string {
+	var sections strings.Builder
+	for i, section := range digest.Sections {
+		if i > 0 {
+			sections.WriteString("\n")
+		}
+		title := section.Title
+		if title == "" {
+			title = b.localizer.GetMessage(lang, "digest_section_general")
+		}
+		sections.WriteString("<b>" + telegramhtml.FromMarkdown(title) + "</b>\n")
+		for _, entry := range section.Entries {
+			line := "• " + html.EscapeString(entry.Item.Title)
+			if news_fetcher.IsWebLink(entry.Item.Link) {
+				line = fmt.Sprintf("• <a href=\"%s\">%s</a>", html.EscapeString(entry.Item.Link), html.EscapeString(entry.Item.Title))
+			}
+			if entry.Summary != "" {
+				line += " — " + telegramhtml.FromMarkdown(entry.Summary)
+			}
+			sections.WriteString(line + "\n")
+		}
+	}
+
+	template := cfg.DigestTemplate
+	if template == "" {
+		template = config.DefaultDigestTemplate
+	}
+	text := strings.NewReplacer(
+		"{date}", b.formatDate(lang, time.Now()),
+		"{intro}", telegramhtml.FromMarkdown(digest.Intro),
+		"{sections}", strings.TrimSpace(sections.String()),
+		"{count}", strconv.Itoa(count),
+		"{topic_name}", html.EscapeString(topicName),
+	).Replace(template)
+	return strings.TrimSpace(extraBlankLines.ReplaceAllString(text, "\n\n"))
+}
+
+// formatDate writes a date with the month names and word order of lang.
+func (b *TelegramBot) formatDate(lang string, date time.Time) string {
+	month := date.Month().String()
+	if months := strings.Split(b.localizer.GetMessage(lang, "month_names"), ","); len(months) == 12 {
+		month = strings.TrimSpace(months[date.Month()-1])
+	}
+	return strings.NewReplacer(
+		"{day}", strconv.Itoa(date.Day()),
+		"{month}", month,
+		"{year}", strconv.Itoa(date.Year()),
+	).Replace(b.localizer.GetMessage(lang, "date_format"))
+}
+
+// digestDestination returns where a digest is posted: the topic's
+// destination for topic digests, otherwise the chat itself.
+func (b *TelegramBot) digestDestination(chatID int64, topicID int64) (int64, int) {
+	if topicID == 0 {
+		return chatID, 0
+	}
+	topics, err := b.storage.GetTopicsForChat(chatID)
+	if err != nil {
+		log.Printf("[Chat %d] Could not load topics for the digest destination: %v", chatID, err)
+		return chatID, 0
+	}
+	for _, topic := range topics {
+		if topic.ID == topicID && topic.DestinationChatID != 0 {
+			return topic.DestinationChatID, int(topic.ReplyToMessageID)
+		}
+	}
+	return chatID, 0
+}
+
+func (b *TelegramBot) postDigest(chatID int64, topicID int64, text string) error {
+	destination, replyToID := b.digestDestination(chatID, topicID)
+	for _, part := range telegramhtml.Split(text, telegramMessageLimit) {
+		if _, err := b.sendTextPost(destination, replyToID, part); err != nil {
+			return err
+		}
+	}
+	log.Printf("[Chat %d] Digest posted.", chatID)
+	return nil
+}
+
+func (b *TelegramBot) sendDigestToModeration(chatID int64, topicID int64, cfg *config.Config, text string) error {
+	id, err := b.storage.AddPendingDigest(storage.PendingDigest{ChatID: chatID, TopicID: topicID, Text: text})
+	if err != nil {
+		return err
+	}
+	target := cfg.ApprovalChatID
+	if target == 0 {
+		target = chatID
+	}
+	lang := b.getLangForChat(chatID)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_approve"), fmt.Sprintf("approve_digest:%d", id)),
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_reject"), fmt.Sprintf("reject_digest:%d", id)),
+		),
+	)
+	parts := telegramhtml.Split(b.localizer.GetMessage(lang, "digest_approval_header")+"\n\n"+text, telegramMessageLimit)
+	for i, part := range parts {
+		msg := tgbotapi.NewMessage(target, part)
+		msg.ParseMode = tgbotapi.ModeHTML
+		msg.DisableWebPagePreview = true
+		if i == len(parts)-1 {
+			msg.ReplyMarkup = &keyboard
+		}
+		if _, err := b.api.Send(msg); err != nil {
+			b.storage.DeletePendingDigest(id)
+			return fmt.Errorf("failed to send digest for approval: %w", err)
+		}
+	}
+	log.Printf("[Chat %d] Digest %d sent for approval.", chatID, id)
+	return nil
+}
+
+func (b *TelegramBot) handleDigestModeration(callback *tgbotapi.CallbackQuery, action string, data string) {
+	digestID, _ := strconv.ParseInt(data, 10, 64)
+	digest, err := b.storage.GetPendingDigest(digestID)
+	if err != nil {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, "This digest has already been processed."))
+		return
+	}
+	lang := b.getLangForChat(digest.ChatID)
+	if !b.isChatAdmin(digest.ChatID, callback.From.ID) {
+		b.api.Request(tgbotapi.NewCallback(callback.ID, b.localizer.GetMessage(lang, "permission_denied")))
+		return
+	}
+
+	answer := b.localizer.GetMessage(lang, "digest_rejected")
+	if action == "approve_digest" {
+		if err := b.postDigest(digest.ChatID, digest.TopicID, digest.Text); err != nil {
+			log.Printf("[Chat %d] Failed to post approved digest %d: %v", digest.ChatID, digestID, err)
+			b.api.Request(tgbotapi.NewCallback(callback.ID, b.localizer.GetMessage(lang, "digest_publish_failed")))
+			return
+		}
+		answer = b.localizer.GetMessage(lang, "digest_approved")
+	}
+	b.storage.DeletePendingDigest(digestID)
+	b.api.Request(tgbotapi.NewEditMessageReplyMarkup(callback.Message.Chat.ID, callback.Message.MessageID, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}))
+	b.api.Request(tgbotapi.NewCallback(callback.ID, answer))
+}
+
+func (b *TelegramBot) sendDigestMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for digest menu for chat %d: %v", chatID, err)
+		return
+	}
+	topics, err := b.storage.GetTopicsForChat(chatID)
+	if err != nil {
+		log.Printf("Failed to get topics for digest menu for chat %d: %v", chatID, err)
+	}
+	queued, err := b.storage.CountDigestItems(chatID)
+	if err != nil {
+		log.Printf("Failed to count digest queue for chat %d: %v", chatID, err)
+	}
+
+	status := func(on bool) string {
+		if on {
+			return b.localizer.GetMessage(lang, "status_on")
+		}
+		return b.localizer.GetMessage(lang, "status_off")
+	}
+	templateStatus := b.localizer.GetMessage(lang, "digest_template_default")
+	if cfg.DigestTemplate != "" && cfg.DigestTemplate != b.defaultChatCfg.DigestTemplate {
+		templateStatus = b.localizer.GetMessage(lang, "digest_template_custom")
+	}
+	schedule := cfg.DigestSchedule
+	if schedule == "" {
+		schedule = config.DefaultDigestSchedule
+	}
+	text := fmt.Sprintf(b.localizer.GetMessage(lang, "digest_menu_title"), status(cfg.DigestEnabled), html.EscapeString(schedule), cfg.DigestWindowHours, status(cfg.DigestApproval), templateStatus, queued)
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(b.localizer.GetMessage(lang, "btn_toggle_digest"), status(cfg.DigestEnabled)), "toggle_digest"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_digest_daily"), "digest_preset:daily"),
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_digest_weekly"), "digest_preset:weekly"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_edit_digest_schedule"), "edit_digest_schedule"),
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_edit_digest_window"), "edit_digest_window"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(b.localizer.GetMessage(lang, "btn_toggle_digest_approval"), status(cfg.DigestApproval)), "toggle_digest_approval"),
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_edit_digest_template"), "edit_digest_template"),
+		),
+	}
+	for _, topic := range topics {
+		label := "▫️ " + topic.Name
+		if topic.DigestMode {
+			label = "✅ " + topic.Name
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("toggle_topic_digest:%d", topic.ID)),
+		))
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_digest_now"), "digest_now"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_main_settings"), "back_to_settings"),
+		),
+	)
+	b.sendOrEditMenu(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+func (b *TelegramBot) handleDigestCallback(callback *tgbotapi.CallbackQuery, action string, data string) {
+	userID := callback.From.ID
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	lang := b.getLangForChat(chatID)
+
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for chat %d: %v", chatID, err)
+		return
+	}
+
+	switch action {
+	case "digest_menu":
+		b.clearUserState(userID)
+	case "toggle_digest":
+		if err := b.storage.UpdateChatConfig(chatID, "digest_enabled", !cfg.DigestEnabled); err != nil {
+			log.Printf("Failed to update digest_enabled for chat %d: %v", chatID, err)
+		}
+		b.refreshDigestSchedule(chatID)
+	case "toggle_digest_approval":
+		if err := b.storage.UpdateChatConfig(chatID, "digest_approval", !cfg.DigestApproval); err != nil {
+			log.Printf("Failed to update digest_approval for chat %d: %v", chatID, err)
+		}
+	case "digest_preset":
+		schedule, window := config.DefaultDigestSchedule, 24
+		if data == "weekly" {
+			schedule, window = weeklyDigestCron, 7*24
+		}
+		if err := b.storage.UpdateChatConfig(chatID, "digest_schedule", schedule); err != nil {
+			log.Printf("Failed to update digest_schedule for chat %d: %v", chatID, err)
+		}
+		if err := b.storage.UpdateChatConfig(chatID, "digest_window_hours", window); err != nil {
+			log.Printf("Failed to update digest_window_hours for chat %d: %v", chatID, err)
+		}
+		b.refreshDigestSchedule(chatID)
+	case "toggle_topic_digest":
+		topicID, _ := strconv.ParseInt(data, 10, 64)
+		enabled := !b.digestTopicIDs(chatID)[topicID]
+		if err := b.storage.SetTopicDigestMode(topicID, chatID, enabled); err != nil {
+			log.Printf("Failed to update digest mode of topic %d for chat %d: %v", topicID, chatID, err)
+		}
+		b.refreshDigestSchedule(chatID)
+	case "digest_now":
+		go b.publishDigests(chatID, true)
+		b.api.Request(tgbotapi.NewCallback(callback.ID, b.localizer.GetMessage(lang, "digest_now_started")))
+		return
+	case "edit_digest_schedule", "edit_digest_window", "edit_digest_template":
+		steps := map[string]string{
+			"edit_digest_schedule": StateAwaitingDigestSchedule,
+			"edit_digest_window":   StateAwaitingDigestWindow,
+			"edit_digest_template": StateAwaitingDigestTemplate,
+		}
+		prompts := map[string]string{
+			"edit_digest_schedule": "ask_digest_schedule",
+			"edit_digest_window":   "ask_digest_window",
+			"edit_digest_template": "ask_digest_template",
+		}
+		b.setUserState(userID, &ConversationState{Step: steps[action]})
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, b.localizer.GetMessage(lang, prompts[action]))
+		editMsg.ParseMode = tgbotapi.ModeHTML
+		b.api.Send(editMsg)
+		return
+	}
+	b.sendDigestMenu(chatID, messageID)
+}
+
+// updateDigestSetting validates and saves a digest setting sent as a message.
+// It returns the error message to show, if any.
+func (b *TelegramBot) updateDigestSetting(chatID int64, step string, text string) string {
+	lang := b.getLangForChat(chatID)
+	text = strings.TrimSpace(text)
+	var column string
+	var value interface{}
+	switch step {
+	case StateAwaitingDigestSchedule:
+		if err := scheduler.ValidateCron(text); err != nil {
+			return fmt.Sprintf(b.localizer.GetMessage(lang, "invalid_digest_schedule"), html.EscapeString(err.Error()))
+		}
+		column, value = "digest_schedule", text
+	case StateAwaitingDigestWindow:
+		hours, err := strconv.Atoi(text)
+		if err != nil || hours <= 0 || hours > maxDigestWindow {
+			return b.localizer.GetMessage(lang, "invalid_input_not_a_number")
+		}
+		column, value = "digest_window_hours", hours
+	case StateAwaitingDigestTemplate:
+		if !strings.Contains(text, "{sections}") {
+			return b.localizer.GetMessage(lang, "invalid_digest_template")
+		}
+		column, value = "digest_template", text
+	}
+	if err := b.storage.UpdateChatConfig(chatID, column, value); err != nil {
+		log.Printf("Failed to update %s for chat %d: %v", column, chatID, err)
+		return b.localizer.GetMessage(lang, "settings_error")
+	}
+	if column == "digest_schedule" {
+		b.refreshDigestSchedule(chatID)
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"news-bot/config"
+	"news-bot/internal/ai"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxModelFallbacks bounds the fallback chain, since every entry may cost a
+// failed request per article.
+const maxModelFallbacks = 3
+
+// modelRef names a model of a provider.
+type modelRef struct {
+	Provider string
+	Model    string
+}
+
+func (r modelRef) String() string {
+	return r.Provider + "/" + r.Model
+}
+
+// parseModelRef reads "provider/model". Model names may contain slashes
+// themselves, provider names never do.
+func parseModelRef(text string) (modelRef, bool) {
+	provider, model, ok := strings.Cut(strings.TrimSpace(text), "/")
+	if !ok || provider == "" || model == "" || strings.ContainsAny(model, " \n") {
+		return modelRef{}, false
+	}
+	return modelRef{Provider: strings.ToLower(provider), Model: model}, true
+}
+
+// modelFallbacks returns the chat's fallback chain, stored one entry per line.
+func modelFallbacks(chatCfg *config.Config) []modelRef {
+	var refs []modelRef
+	for _, line := range strings.Split(chatCfg.ModelFallbacks, "\n") {
+		if ref, ok := parseModelRef(line); ok {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+func formatModelFallbacks(refs []modelRef) string {
+	lines := make([]string, len(refs))
+	for i, ref := range refs {
+		lines[i] = ref.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// allProvidersPaused reports whether the providers of the chat's model and of
+// all its fallbacks are paused, and until when the first of them is.
+func (b *TelegramBot) allProvidersPaused(chatCfg *config.Config) (time.Time, bool) {
+	var earliest time.Time
+	refs := append([]modelRef{{Provider: chatProvider(chatCfg), Model: chatCfg.AIModel}}, modelFallbacks(chatCfg)...)
+	for _, ref := range refs {
+		until, paused := ai.PausedUntil(ref.Provider, b.chatProviderConfig(chatCfg, ref.Provider))
+		if !paused {
+			return time.Time{}, false
+		}
+		if earliest.IsZero() || until.Before(earliest) {
+			earliest = until
+		}
+	}
+	return earliest, true
+}
+
+func (b *TelegramBot) sendModelFallbacksMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for fallback menu for chat %d: %v", chatID, err)
+		return
+	}
+
+	fallbacks := modelFallbacks(cfg)
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "model_fallbacks_title"), html.EscapeString(chatProvider(cfg)+"/"+cfg.AIModel)))
+	if len(fallbacks) == 0 {
+		builder.WriteString(b.localizer.GetMessage(lang, "model_fallbacks_empty"))
+	}
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, ref := range fallbacks {
+		builder.WriteString(fmt.Sprintf("%d. <code>%s</code>\n", i+1, html.EscapeString(ref.String())))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("🗑 %d. %s", i+1, ref.String()), fmt.Sprintf("delete_fallback:%d", i)),
+		))
+	}
+	builder.WriteString(b.localizer.GetMessage(lang, "model_fallbacks_extractive_note"))
+
+	if len(fallbacks) < maxModelFallbacks {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_add_fallback"), "add_fallback"),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_menu"), "edit_gemini_model"),
+	))
+	b.sendOrEditMenu(chatID, messageID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+func (b *TelegramBot) handleModelFallbacksCallback(callback *tgbotapi.CallbackQuery, action string, data string) {
+	userID := callback.From.ID
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	lang := b.getLangForChat(chatID)
+
+	switch action {
+	case "add_fallback":
+		b.setUserState(userID, &ConversationState{Step: StateAwaitingFallbackModel})
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf(b.localizer.GetMessage(lang, "ask_fallback_model"), strings.Join(b.configuredProviders(nil), ", ")))
+		editMsg.ParseMode = tgbotapi.ModeHTML
+		b.api.Send(editMsg)
+		return
+	case "delete_fallback":
+		index, err := strconv.Atoi(data)
+		if err != nil {
+			return
+		}
+		cfg, err := b.storage.GetChatConfig(chatID)
+		if err != nil {
+			log.Printf("Failed to get chat config for chat %d: %v", chatID, err)
+			return
+		}
+		fallbacks := modelFallbacks(cfg)
+		if index < 0 || index >= len(fallbacks) {
+			return
+		}
+		fallbacks = append(fallbacks[:index], fallbacks[index+1:]...)
+		if err := b.storage.UpdateChatConfig(chatID, "model_fallbacks", formatModelFallbacks(fallbacks)); err != nil {
+			log.Printf("Failed to update model_fallbacks for chat %d: %v", chatID, err)
+		}
+	}
+	b.sendModelFallbacksMenu(chatID, messageID)
+}
+
+// addModelFallback validates a "provider/model" entry sent by a user, tests
+// it and appends it to the chat's chain. It returns an error message for the
+// user, or an empty string on success.
+func (b *TelegramBot) addModelFallback(chatID int64, text string) string {
+	lang := b.getLangForChat(chatID)
+	ref, ok := parseModelRef(text)
+	if !ok || !isKnownProvider(ref.Provider) {
+		return b.localizer.GetMessage(lang, "invalid_fallback_model")
+	}
+	model, ok := b.resolveModel(ref.Provider, ref.Model)
+	if !ok {
+		return fmt.Sprintf(b.localizer.GetMessage(lang, "model_not_allowed"), html.EscapeString(ref.Model))
+	}
+	ref.Model = model
+
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for chat %d: %v", chatID, err)
+		return b.localizer.GetMessage(lang, "settings_error")
+	}
+	fallbacks := modelFallbacks(cfg)
+	if len(fallbacks) >= maxModelFallbacks {
+		return b.localizer.GetMessage(lang, "model_fallbacks_full")
+	}
+
+	b.api.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, "model_testing"), ref.String())))
+	if err := b.testModel(chatID, ref.Provider, ref.Model); err != nil {
+		return fmt.Sprintf(b.localizer.GetMessage(lang, "model_test_failed"), html.EscapeString(ref.String()), html.EscapeString(err.Error()))
+	}
+
+	fallbacks = append(fallbacks, ref)
+	if err := b.storage.UpdateChatConfig(chatID, "model_fallbacks", formatModelFallbacks(fallbacks)); err != nil {
+		log.Printf("Failed to update model_fallbacks for chat %d: %v", chatID, err)
+		return b.localizer.GetMessage(lang, "settings_error")
+	}
+	return ""
+}
@@ -0,0 +1,254 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"news-bot/internal/filter"
+	"news-bot/internal/news_fetcher"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func candidateFromStub(stub news_fetcher.DiscoveredArticle) filter.Candidate {
+	return filter.Candidate{
+		Title:       stub.Title,
+		Description: stub.Description,
+		Categories:  stub.Categories,
+		Author:      stub.Author,
+		Link:        stub.Link,
+		TopicID:     stub.Source.TopicID,
+		SourceID:    stub.Source.ID,
+	}
+}
+
+// candidateFromArticle adds the fetched article to its feed entry. The entry's
+// metadata is kept, so that an include rule that matched before fetching
+// still matches.
+func candidateFromArticle(stub news_fetcher.DiscoveredArticle, article *news_fetcher.Article) filter.Candidate {
+	candidate := candidateFromStub(stub)
+	text := []string{article.TextContent}
+	if article.Title != "" && article.Title != stub.Title {
+		text = append(text, article.Title)
+	}
+	if article.Description != "" && article.Description != stub.Description {
+		text = append(text, article.Description)
+	}
+	candidate.Text = strings.Join(text, "\n")
+	if article.Author != "" && article.Author != candidate.Author {
+		candidate.Author = strings.TrimPrefix(candidate.Author+", "+article.Author, ", ")
+	}
+	return candidate
+}
+
+// isBlockedByFilters evaluates the chat's filter rules and records the block.
+// Blocked articles are marked as posted so they are not evaluated every cycle.
+func (b *TelegramBot) isBlockedByFilters(chatID int64, rules []filter.Rule, candidate filter.Candidate, stage filter.Stage) bool {
+	rule := filter.Evaluate(rules, candidate, stage)
+	if rule == nil {
+		return false
+	}
+
+	log.Printf("[Chat %d] Article '%s' blocked by filter rule %d (%s %s '%s').", chatID, candidate.Link, rule.ID, rule.Action, rule.Kind, rule.Pattern)
+	if err := b.storage.IncrementFilterBlockedCount(rule.ID); err != nil {
+		log.Printf("[Chat %d] Failed to update blocked count of filter rule %d: %v", chatID, rule.ID, err)
+	}
+	rule.BlockedCount++
+	b.storage.MarkAsPosted(candidate.Link, chatID)
+	return true
+}
+
+func (b *TelegramBot) describeFilterRule(lang string, rule filter.Rule, topics map[int64]string, sources map[int64]string) string {
+	scope := b.localizer.GetMessage(lang, "filter_scope_chat")
+	switch rule.Scope {
+	case filter.ScopeTopic:
+		scope = fmt.Sprintf(b.localizer.GetMessage(lang, "filter_scope_topic_named"), topics[rule.ScopeID])
+	case filter.ScopeSource:
+		scope = fmt.Sprintf(b.localizer.GetMessage(lang, "filter_scope_source_named"), sources[rule.ScopeID])
+	}
+
+	icon := "✅"
+	if rule.Action == filter.ActionExclude {
+		icon = "🚫"
+	}
+	return fmt.Sprintf("%s %s %s · %s\n<code>%s</code>", icon,
+		b.localizer.GetMessage(lang, "filter_action_"+rule.Action),
+		b.localizer.GetMessage(lang, "filter_kind_"+rule.Kind),
+		scope,
+		html.EscapeString(rule.Pattern))
+}
+
+func (b *TelegramBot) filterScopeNames(chatID int64) (map[int64]string, map[int64]string) {
+	topicNames := make(map[int64]string)
+	if topics, err := b.storage.GetTopicsForChat(chatID); err == nil {
+		for _, topic := range topics {
+			topicNames[topic.ID] = topic.Name
+		}
+	}
+	sourceNames := make(map[int64]string)
+	if sources, err := b.storage.GetNewsSourcesForChat(chatID); err == nil {
+		for _, source := range sources {
+			sourceNames[source.ID] = shortenForButton(news_fetcher.DisplayURL(source.URL), 40)
+		}
+	}
+	return topicNames, sourceNames
+}
+
+func (b *TelegramBot) sendFiltersMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	rules, err := b.storage.GetFilterRulesForChat(chatID)
+	if err != nil {
+		log.Printf("Failed to get filter rules for chat %d: %v", chatID, err)
+		return
+	}
+	topicNames, sourceNames := b.filterScopeNames(chatID)
+
+	var builder strings.Builder
+	builder.WriteString(b.localizer.GetMessage(lang, "filters_menu_title"))
+	if len(rules) == 0 {
+		builder.WriteString(b.localizer.GetMessage(lang, "filters_none"))
+	}
+	for _, rule := range rules {
+		builder.WriteString(b.describeFilterRule(lang, rule, topicNames, sourceNames))
+		builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "filter_blocked_count"), rule.BlockedCount))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_add_filter"), "add_filter"),
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_delete_filter"), "delete_filter_menu"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_main_settings"), "back_to_settings"),
+		),
+	)
+	b.sendOrEditMenu(chatID, messageID, builder.String(), keyboard)
+}
+
+func (b *TelegramBot) sendFilterActionMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "filter_action_include"), "filter_action:"+filter.ActionInclude),
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "filter_action_exclude"), "filter_action:"+filter.ActionExclude),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_cancel"), "manage_filters"),
+		),
+	)
+	b.sendOrEditMenu(chatID, messageID, b.localizer.GetMessage(lang, "ask_filter_action"), keyboard)
+}
+
+func (b *TelegramBot) sendFilterKindMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, kind := range []string{filter.KindKeyword, filter.KindRegex, filter.KindAuthor, filter.KindDomain} {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "filter_kind_"+kind), "filter_kind:"+kind),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_cancel"), "manage_filters"),
+	))
+	b.sendOrEditMenu(chatID, messageID, b.localizer.GetMessage(lang, "ask_filter_kind"), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+func (b *TelegramBot) sendFilterScopeMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "filter_scope_chat"), "filter_scope:chat:0"),
+		),
+	}
+
+	topics, _ := b.storage.GetTopicsForChat(chatID)
+	for _, topic := range topics {
+		label := fmt.Sprintf(b.localizer.GetMessage(lang, "filter_scope_topic_named"), topic.Name)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("filter_scope:topic:%d", topic.ID)),
+		))
+	}
+	sources, _ := b.storage.GetNewsSourcesForChat(chatID)
+	for _, source := range sources {
+		label := fmt.Sprintf(b.localizer.GetMessage(lang, "filter_scope_source_named"), shortenForButton(news_fetcher.DisplayURL(source.URL), 30))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("filter_scope:source:%d", source.ID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_cancel"), "manage_filters"),
+	))
+	b.sendOrEditMenu(chatID, messageID, b.localizer.GetMessage(lang, "ask_filter_scope"), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+func (b *TelegramBot) sendDeleteFilterMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	rules, err := b.storage.GetFilterRulesForChat(chatID)
+	if err != nil {
+		log.Printf("Failed to get filter rules for deletion for chat %d: %v", chatID, err)
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, rule := range rules {
+		label := fmt.Sprintf("❌ %s %s: %s", b.localizer.GetMessage(lang, "filter_action_"+rule.Action), b.localizer.GetMessage(lang, "filter_kind_"+rule.Kind), shortenForButton(rule.Pattern, 30))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("delete_filter:%d", rule.ID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_menu"), "manage_filters"),
+	))
+	b.sendOrEditMenu(chatID, messageID, b.localizer.GetMessage(lang, "delete_filter_title"), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// handleFilterCallback drives the "add filter" wizard: action, kind, scope, then pattern.
+func (b *TelegramBot) handleFilterCallback(callback *tgbotapi.CallbackQuery, action string, data string) {
+	userID := callback.From.ID
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	lang := b.getLangForChat(chatID)
+
+	switch action {
+	case "add_filter":
+		b.setUserState(userID, &ConversationState{Step: StateBuildingFilter, PendingFilter: filter.Rule{ChatID: chatID}})
+		b.sendFilterActionMenu(chatID, messageID)
+		return
+	case "delete_filter":
+		ruleID, _ := strconv.ParseInt(data, 10, 64)
+		if err := b.storage.DeleteFilterRule(ruleID, chatID); err != nil {
+			log.Printf("Failed to delete filter rule %d for chat %d: %v", ruleID, chatID, err)
+		}
+		b.api.Request(tgbotapi.NewCallback(callback.ID, b.localizer.GetMessage(lang, "filter_deleted_success")))
+		b.sendDeleteFilterMenu(chatID, messageID)
+		return
+	}
+
+	state, ok := b.getUserState(userID)
+	if !ok || state.Step != StateBuildingFilter {
+		b.sendFiltersMenu(chatID, messageID)
+		return
+	}
+
+	switch action {
+	case "filter_action":
+		state.PendingFilter.Action = data
+		b.sendFilterKindMenu(chatID, messageID)
+	case "filter_kind":
+		state.PendingFilter.Kind = data
+		b.sendFilterScopeMenu(chatID, messageID)
+	case "filter_scope":
+		parts := strings.SplitN(data, ":", 2)
+		state.PendingFilter.Scope = parts[0]
+		if len(parts) == 2 {
+			state.PendingFilter.ScopeID, _ = strconv.ParseInt(parts[1], 10, 64)
+		}
+		state.Step = StateAwaitingFilterPattern
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, b.localizer.GetMessage(lang, "ask_filter_pattern_"+state.PendingFilter.Kind))
+		editMsg.ParseMode = tgbotapi.ModeHTML
+		b.api.Send(editMsg)
+	}
+	b.setUserState(userID, state)
+}
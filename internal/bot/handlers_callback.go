@@ -3,6 +3,7 @@ package bot
 import (
 	"fmt"
 	"log"
+	"news-bot/config"
 	"news-bot/internal/news_fetcher"
 	"strconv"
 	"strings"
@@ -57,6 +58,7 @@ func (b *TelegramBot) handleCallbackQuery(callback *tgbotapi.CallbackQuery) {
 	case "edit_ai_prompt":
 		b.setUserState(userID, &ConversationState{Step: StateAwaitingAIPrompt})
 		msg.Text = b.localizer.GetMessage(lang, "ask_for_new_ai_prompt")
+		msg.ParseMode = tgbotapi.ModeHTML
 		b.api.Send(msg)
 	case "edit_post_limit":
 		b.setUserState(userID, &ConversationState{Step: StateAwaitingPostLimit})
@@ -90,17 +92,75 @@ func (b *TelegramBot) handleCallbackQuery(callback *tgbotapi.CallbackQuery) {
 		b.api.Request(tgbotapi.NewDeleteMessage(chatID, messageID))
 		b.handleSettingsCommand(callback.Message)
 
+	case "toggle_blocked_policy":
+		cfg, err := b.storage.GetChatConfig(chatID)
+		if err != nil {
+			log.Printf("Error getting chat config for %d: %v", chatID, err)
+			return
+		}
+		newValue := config.BlockedPolicyDescription
+		if cfg.BlockedContentPolicy == config.BlockedPolicyDescription {
+			newValue = config.BlockedPolicyModeration
+		}
+		if err := b.storage.UpdateChatConfig(chatID, "blocked_content_policy", newValue); err != nil {
+			log.Printf("Failed to update blocked_content_policy for chat %d: %v", chatID, err)
+		}
+		b.api.Request(tgbotapi.NewDeleteMessage(chatID, messageID))
+		b.handleSettingsCommand(callback.Message)
+
+	case "toggle_article_qa":
+		cfg, err := b.storage.GetChatConfig(chatID)
+		if err != nil {
+			log.Printf("Error getting chat config for %d: %v", chatID, err)
+			return
+		}
+		if err := b.storage.UpdateChatConfig(chatID, "article_qa_enabled", !cfg.ArticleQAEnabled); err != nil {
+			log.Printf("Failed to update article_qa_enabled for chat %d: %v", chatID, err)
+		}
+		b.api.Request(tgbotapi.NewDeleteMessage(chatID, messageID))
+		b.handleSettingsCommand(callback.Message)
+
+	case "toggle_verification":
+		cfg, err := b.storage.GetChatConfig(chatID)
+		if err != nil {
+			log.Printf("Error getting chat config for %d: %v", chatID, err)
+			return
+		}
+		newValue := config.VerificationMatching
+		switch cfg.SummaryVerification {
+		case config.VerificationMatching:
+			newValue = config.VerificationModel
+		case config.VerificationModel:
+			newValue = config.VerificationOff
+		}
+		if err := b.storage.UpdateChatConfig(chatID, "summary_verification", newValue); err != nil {
+			log.Printf("Failed to update summary_verification for chat %d: %v", chatID, err)
+		}
+		b.api.Request(tgbotapi.NewDeleteMessage(chatID, messageID))
+		b.handleSettingsCommand(callback.Message)
+
 	case "edit_approval_chat_id":
 		b.setUserState(userID, &ConversationState{Step: StateAwaitingApprovalChatID})
 		msg.Text = b.localizer.GetMessage(lang, "ask_for_approval_chat_id")
 		b.api.Send(msg)
 
-	case "set_gemini_model":
-		if err := b.storage.UpdateChatConfig(chatID, "gemini_model", data); err != nil {
-			log.Printf("Failed to update gemini_model in db for chat %d: %v", chatID, err)
+	case "select_ai_provider":
+		b.sendProviderModelMenu(chatID, messageID, data)
+	case "enter_ai_model":
+		b.setUserState(userID, &ConversationState{Step: StateAwaitingModelName, PendingProvider: data})
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf(b.localizer.GetMessage(lang, "ask_for_model_name"), b.localizer.GetMessage(lang, "ai_provider_"+data)))
+		editMsg.ParseMode = tgbotapi.ModeHTML
+		b.api.Send(editMsg)
+	case "set_ai_model":
+		parts := strings.SplitN(data, ":", 2)
+		if len(parts) != 2 {
+			return
 		}
-		successMsg := tgbotapi.NewEditMessageText(chatID, messageID, b.localizer.GetMessage(lang, "setting_updated_success"))
-		b.api.Send(successMsg)
+		b.api.Send(tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf(b.localizer.GetMessage(lang, "model_testing"), parts[1])))
+		resultText, _ := b.applyModelChoice(chatID, parts[0], parts[1])
+		resultMsg := tgbotapi.NewEditMessageText(chatID, messageID, resultText)
+		resultMsg.ParseMode = tgbotapi.ModeHTML
+		b.api.Send(resultMsg)
 
 	case "refresh_settings":
 		b.api.Request(tgbotapi.NewDeleteMessage(chatID, messageID))
@@ -130,7 +190,12 @@ func (b *TelegramBot) handleCallbackQuery(callback *tgbotapi.CallbackQuery) {
 		sourceType := data
 		state := &ConversationState{Step: StateAwaitingSourceURL, PendingSource: news_fetcher.Source{Type: sourceType}}
 		b.setUserState(userID, state)
-		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, b.localizer.GetMessage(lang, "ask_source_url"))
+		promptKey := "ask_source_url"
+		if sourceType == "email" {
+			promptKey = "ask_source_url_email"
+		}
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, b.localizer.GetMessage(lang, promptKey))
+		editMsg.ParseMode = tgbotapi.ModeHTML
 		b.api.Send(editMsg)
 	case "chose_topic_for_source":
 		topicID, _ := strconv.ParseInt(data, 10, 64)
@@ -181,6 +246,42 @@ func (b *TelegramBot) handleCallbackQuery(callback *tgbotapi.CallbackQuery) {
 			b.sendDeleteTopicMenu(chatID, messageID)
 		}
 
+	case "manage_filters":
+		b.clearUserState(userID)
+		b.sendFiltersMenu(chatID, messageID)
+	case "delete_filter_menu":
+		b.sendDeleteFilterMenu(chatID, messageID)
+	case "add_filter", "filter_action", "filter_kind", "filter_scope", "delete_filter":
+		b.handleFilterCallback(callback, action, data)
+	case "category_mappings", "catmap_source", "add_catmap", "delete_catmap", "catmap_topic":
+		b.handleCategoryMappingCallback(callback, action, data)
+
+	case "model_fallbacks":
+		b.clearUserState(userID)
+		b.sendModelFallbacksMenu(chatID, messageID)
+	case "add_fallback", "delete_fallback":
+		b.handleModelFallbacksCallback(callback, action, data)
+
+	case "digest_menu", "toggle_digest", "digest_preset", "edit_digest_schedule", "edit_digest_window", "toggle_digest_approval", "edit_digest_template", "toggle_topic_digest", "digest_now":
+		b.handleDigestCallback(callback, action, data)
+	case "approve_digest", "reject_digest":
+		b.handleDigestModeration(callback, action, data)
+
+	case "classification_menu", "toggle_classification", "toggle_classification_fallback", "edit_classification_threshold":
+		b.handleClassificationCallback(callback, action)
+	case "relevance_menu", "edit_interest_profile", "edit_relevance_threshold", "clear_interest_profile":
+		b.handleRelevanceCallback(callback, action)
+	case "prompt_presets", "use_preset", "delete_preset", "save_preset", "share_preset_menu", "share_preset":
+		b.handlePromptPresetCallback(callback, action, data)
+	case "summary_style_menu", "cycle_summary_length", "cycle_summary_format", "cycle_summary_emoji", "edit_summary_max_chars":
+		b.handleSummaryStyleCallback(callback, action)
+
+	case "manage_languages":
+		b.clearUserState(userID)
+		b.sendLanguagePolicyMenu(chatID, messageID)
+	case "lang_mode", "edit_source_languages", "toggle_translate_title", "summary_language_menu", "set_summary_language":
+		b.handleLanguagePolicyCallback(callback, action, data)
+
 	case "approve_article":
 		b.handleApproveArticle(callback)
 	case "reject_article":
@@ -234,6 +335,12 @@ func (b *TelegramBot) handleApproveArticle(callback *tgbotapi.CallbackQuery) {
 		Link:            pendingArticle.Link,
 		ImageURL:        pendingArticle.ImageURL,
 		PublicationTime: &pendingArticle.CreatedAt,
+		MediaURL:        pendingArticle.MediaURL,
+		MediaType:       pendingArticle.MediaType,
+		MediaSize:       pendingArticle.MediaSize,
+		Duration:        pendingArticle.Duration,
+		Language:        pendingArticle.Language,
+		TextContent:     pendingArticle.ArticleText,
 	}
 
 	var source news_fetcher.Source
@@ -253,12 +360,12 @@ func (b *TelegramBot) handleApproveArticle(callback *tgbotapi.CallbackQuery) {
 		}
 	}
 
-	if err := b.sendArticleToChannel(articleToPost, pendingArticle.Summary, source, chatCfg); err != nil {
+	if err := b.sendArticleToChannel(articleToPost, summaryFromPending(pendingArticle), source, chatCfg); err != nil {
 		log.Printf("Failed to send approved article to channel for chat %d: %v", pendingArticle.ChatID, err)
 		return
 	}
 
-	if err := b.storage.MarkAsPosted(pendingArticle.Link, pendingArticle.ChatID); err != nil {
+	if err := b.storage.RecordPostedArticle(pendingArticle.Link, pendingArticle.ChatID, pendingArticle.Language, pendingArticle.Model); err != nil {
 		log.Printf("CRITICAL: Failed to mark approved article as posted for chat %d: %v", pendingArticle.ChatID, err)
 	}
 	b.storage.DeletePendingArticle(articleID)
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"news-bot/config"
 	"strconv"
 	"strings"
 
@@ -14,23 +15,28 @@ func (b *TelegramBot) handleCommand(message *tgbotapi.Message) {
 	chatID := message.Chat.ID
 	userID := message.From.ID
 
-	if err := b.ensureChatIsConfigured(chatID); err != nil {
-		log.Printf("Critical error ensuring chat config for %d: %v", chatID, err)
-		return
+	cmd := message.Command()
+	// Keys are managed for other chats from a private chat, which should not
+	// take up a free tier place by being configured on the way.
+	keyCommand := cmd == "set_key" || cmd == "remove_key"
+	if !(keyCommand && message.Chat.IsPrivate()) {
+		if err := b.ensureChatIsConfigured(chatID); err != nil {
+			log.Printf("Critical error ensuring chat config for %d: %v", chatID, err)
+			return
+		}
 	}
 
 	lang := b.getLangForChat(chatID)
 	msg := tgbotapi.NewMessage(chatID, "")
-	cmd := message.Command()
 
-	protectedCommands := map[string]bool{"settings": true, "set_target": true, "cancel": true, "lang": true}
+	protectedCommands := map[string]bool{"settings": true, "set_target": true, "cancel": true, "lang": true, "usage": true}
 	if protectedCommands[cmd] && !b.isChatAdmin(chatID, userID) {
 		msg.Text = b.localizer.GetMessage(lang, "permission_denied")
 		b.api.Send(msg)
 		return
 	}
 
-	superAdminCommands := map[string]bool{"fetch_now": true, "fetch_stop": true}
+	superAdminCommands := map[string]bool{"fetch_now": true, "fetch_stop": true, "set_quota": true, "allow_model": true, "remove_model": true, "models": true, "require_byok": true}
 	if superAdminCommands[cmd] && !b.isSuperAdmin(userID) {
 		msg.Text = b.localizer.GetMessage(lang, "permission_denied")
 		b.api.Send(msg)
@@ -68,6 +74,7 @@ func (b *TelegramBot) handleCommand(message *tgbotapi.Message) {
 		b.handleLangCommand(message)
 		return
 	case "settings":
+		b.recordAdminActivity(message.Chat, userID)
 		b.handleSettingsCommand(message)
 		return
 	case "set_target":
@@ -82,6 +89,30 @@ func (b *TelegramBot) handleCommand(message *tgbotapi.Message) {
 	case "cancel":
 		b.handleCancelCommand(message)
 		return
+	case "usage":
+		b.handleUsageCommand(message)
+		return
+	case "set_quota":
+		b.handleSetQuotaCommand(message)
+		return
+	case "allow_model":
+		b.handleAllowModelCommand(message)
+		return
+	case "remove_model":
+		b.handleRemoveModelCommand(message)
+		return
+	case "models":
+		b.handleModelsCommand(message)
+		return
+	case "set_key":
+		b.handleSetKeyCommand(message)
+		return
+	case "remove_key":
+		b.handleRemoveKeyCommand(message)
+		return
+	case "require_byok":
+		b.handleRequireBYOKCommand(message)
+		return
 	case "analyzelinks":
 		if !b.isSuperAdmin(userID) {
 			return
@@ -205,7 +236,7 @@ func (b *TelegramBot) handleSettingsCommand(message *tgbotapi.Message) {
 	builder.WriteString(b.localizer.GetMessage(lang, "settings_title") + "\n\n")
 
 	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_ai_prompt"), cfg.AiPrompt))
-	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_gemini_model"), cfg.GeminiModel))
+	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_gemini_model"), fmt.Sprintf("%s / %s", chatProvider(cfg), cfg.AIModel)))
 	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_post_limit_per_run"), strconv.Itoa(cfg.PostLimitPerRun)))
 	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_schedule_interval_minutes"), fmt.Sprintf("%d minutes", cfg.ScheduleIntervalMinutes)))
 	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_rss_max_age_hours"), fmt.Sprintf("%d hours", cfg.RSSMaxAgeHours)))
@@ -227,10 +258,42 @@ func (b *TelegramBot) handleSettingsCommand(message *tgbotapi.Message) {
 		templateStatus = "Custom"
 	}
 	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_telegram_message_template"), templateStatus))
+	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_source_languages"), b.describeLanguagePolicy(lang, cfg)))
+	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_summary_language"), b.describeSummaryLanguage(lang, cfg)))
+	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_summary_style"), b.describeSummaryStyle(lang, cfg)))
+	blockedPolicy := b.localizer.GetMessage(lang, "blocked_policy_"+config.BlockedPolicyModeration)
+	if cfg.BlockedContentPolicy == config.BlockedPolicyDescription {
+		blockedPolicy = b.localizer.GetMessage(lang, "blocked_policy_"+config.BlockedPolicyDescription)
+	}
+	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_blocked_content_policy"), blockedPolicy))
+	verification := b.localizer.GetMessage(lang, "verification_"+config.VerificationOff)
+	if cfg.SummaryVerification == config.VerificationMatching || cfg.SummaryVerification == config.VerificationModel {
+		verification = b.localizer.GetMessage(lang, "verification_"+cfg.SummaryVerification)
+	}
+	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_summary_verification"), verification))
+	apiKey := b.localizer.GetMessage(lang, "api_key_shared")
+	if cfg.BYOKProvider != "" && cfg.BYOKAPIKey != "" {
+		apiKey = fmt.Sprintf(b.localizer.GetMessage(lang, "api_key_own"), cfg.BYOKProvider)
+	}
+	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_api_key"), apiKey))
+	articleQA := b.localizer.GetMessage(lang, "status_off")
+	if cfg.ArticleQAEnabled {
+		articleQA = b.localizer.GetMessage(lang, "status_on")
+	}
+	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_article_qa"), articleQA))
+	relevanceFilter := b.localizer.GetMessage(lang, "status_off")
+	if cfg.InterestProfile != "" {
+		relevanceFilter = fmt.Sprintf(b.localizer.GetMessage(lang, "relevance_filter_on"), cfg.RelevanceThreshold)
+	}
+	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "settings_format"), b.localizer.GetMessage(lang, "setting_name_relevance"), relevanceFilter))
+	if cfg.InterestProfile != "" {
+		builder.WriteString(b.recentRelevanceDrops(chatID, lang, settingsRelevanceDrops))
+	}
 
 	builder.WriteString(b.localizer.GetMessage(lang, "settings_edit_prompt"))
 	msg := tgbotapi.NewMessage(chatID, builder.String())
 	msg.ParseMode = tgbotapi.ModeHTML
+	msg.DisableWebPagePreview = true
 
 	approvalStatusText := "Enable Approval"
 	if cfg.EnableApprovalSystem {
@@ -239,7 +302,7 @@ func (b *TelegramBot) handleSettingsCommand(message *tgbotapi.Message) {
 
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
-			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_edit_ai_prompt"), "edit_ai_prompt"),
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_edit_ai_prompt"), "prompt_presets"),
 			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_edit_post_limit"), "edit_post_limit"),
 		),
 		tgbotapi.NewInlineKeyboardRow(
@@ -258,6 +321,27 @@ func (b *TelegramBot) handleSettingsCommand(message *tgbotapi.Message) {
 			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_manage_sources"), "manage_sources"),
 			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_manage_topics"), "manage_topics"),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_manage_filters"), "manage_filters"),
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_manage_languages"), "manage_languages"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(b.localizer.GetMessage(lang, "btn_toggle_blocked_policy"), blockedPolicy), "toggle_blocked_policy"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(b.localizer.GetMessage(lang, "btn_toggle_article_qa"), articleQA), "toggle_article_qa"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(b.localizer.GetMessage(lang, "btn_toggle_verification"), verification), "toggle_verification"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_digest"), "digest_menu"),
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_topic_classification"), "classification_menu"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_relevance"), "relevance_menu"),
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_summary_style"), "summary_style_menu"),
+		),
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_refresh"), "refresh_settings"),
 		),
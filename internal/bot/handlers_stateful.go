@@ -2,9 +2,14 @@ package bot
 
 import (
 	"fmt"
+	"html"
 	"log"
+	"news-bot/internal/ai"
+	"news-bot/internal/filter"
 	"news-bot/internal/news_fetcher"
+	"news-bot/internal/telegramhtml"
 	"strconv"
+	"strings"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -28,6 +33,10 @@ func (b *TelegramBot) handleStatefulMessage(message *tgbotapi.Message) {
 
 	switch state.Step {
 	case StateAwaitingAIPrompt:
+		if err := ai.ValidatePromptTemplate(message.Text); err != nil {
+			msg.Text = fmt.Sprintf(b.localizer.GetMessage(lang, "invalid_ai_prompt"), err)
+			break
+		}
 		if err := b.storage.UpdateChatConfig(chatID, "ai_prompt", message.Text); err != nil {
 			log.Printf("Failed to update ai_prompt for chat %d: %v", chatID, err)
 		} else {
@@ -58,6 +67,75 @@ func (b *TelegramBot) handleStatefulMessage(message *tgbotapi.Message) {
 				operationSuccessful = true
 			}
 		}
+	case StateAwaitingModelName:
+		model := strings.TrimSpace(message.Text)
+		if model == "" || strings.ContainsAny(model, " \n") {
+			msg.Text = b.localizer.GetMessage(lang, "invalid_model_name")
+			break
+		}
+		resolved, ok := b.resolveModel(state.PendingProvider, model)
+		if !ok {
+			msg.Text = fmt.Sprintf(b.localizer.GetMessage(lang, "model_not_allowed"), html.EscapeString(model))
+			msg.ParseMode = tgbotapi.ModeHTML
+			break
+		}
+		b.api.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, "model_testing"), resolved)))
+		if resultText, saved := b.applyModelChoice(chatID, state.PendingProvider, resolved); !saved {
+			msg.Text = resultText
+			msg.ParseMode = tgbotapi.ModeHTML
+		} else {
+			operationSuccessful = true
+		}
+	case StateAwaitingFallbackModel:
+		if errText := b.addModelFallback(chatID, message.Text); errText != "" {
+			msg.Text = errText
+			msg.ParseMode = tgbotapi.ModeHTML
+			break
+		}
+		b.clearUserState(userID)
+		b.sendModelFallbacksMenu(chatID, 0)
+	case StateAwaitingDigestSchedule, StateAwaitingDigestWindow, StateAwaitingDigestTemplate:
+		if errText := b.updateDigestSetting(chatID, state.Step, message.Text); errText != "" {
+			msg.Text = errText
+			msg.ParseMode = tgbotapi.ModeHTML
+			break
+		}
+		b.clearUserState(userID)
+		b.sendDigestMenu(chatID, 0)
+	case StateAwaitingTopicThreshold:
+		threshold, err := parseThreshold(message.Text)
+		if err != nil {
+			msg.Text = b.localizer.GetMessage(lang, "invalid_classification_threshold")
+			break
+		}
+		if err := b.storage.UpdateChatConfig(chatID, "classification_threshold", threshold); err != nil {
+			log.Printf("Failed to update classification_threshold for chat %d: %v", chatID, err)
+			msg.Text = b.localizer.GetMessage(lang, "settings_error")
+			break
+		}
+		b.clearUserState(userID)
+		b.sendClassificationMenu(chatID, 0)
+	case StateAwaitingInterestProfile, StateAwaitingMinRelevance:
+		if errText := b.updateRelevanceSetting(chatID, state.Step, message.Text); errText != "" {
+			msg.Text = errText
+			break
+		}
+		b.clearUserState(userID)
+		b.sendRelevanceMenu(chatID, 0)
+	case StateAwaitingPresetName:
+		if errText := b.savePreset(chatID, message.Text); errText != "" {
+			msg.Text = errText
+			break
+		}
+		b.clearUserState(userID)
+		b.sendPromptPresetsMenu(chatID, 0)
+	case StateAwaitingSummaryLength:
+		if errText := b.updateSummaryMaxChars(chatID, message.Text); errText != "" {
+			msg.Text = errText
+			break
+		}
+		b.clearUserState(userID)
+		b.sendSummaryStyleMenu(chatID, 0)
 	case StateAwaitingMessageTemplate:
 		if err := b.storage.UpdateChatConfig(chatID, "message_template", message.Text); err != nil {
 			log.Printf("Failed to update telegram_message_template for chat %d: %v", chatID, err)
@@ -87,7 +165,7 @@ func (b *TelegramBot) handleStatefulMessage(message *tgbotapi.Message) {
 			}
 		}
 	case StateAwaitingArticleEdit:
-		newSummary := message.Text
+		newSummary := telegramhtml.FromMarkdown(message.Text)
 		articleID := state.PendingArticleID
 
 		if err := b.storage.UpdatePendingArticleSummary(articleID, newSummary); err != nil {
@@ -117,9 +195,9 @@ func (b *TelegramBot) handleStatefulMessage(message *tgbotapi.Message) {
 				break
 			}
 
-			articleToFormat := &news_fetcher.Article{Title: pendingArticle.Title, Link: pendingArticle.Link}
+			articleToFormat := &news_fetcher.Article{Title: pendingArticle.Title, Link: pendingArticle.Link, MediaURL: pendingArticle.MediaURL, Duration: pendingArticle.Duration, Language: pendingArticle.Language}
 			sourceToFormat := news_fetcher.Source{URL: "https://" + pendingArticle.SourceName, TopicName: pendingArticle.TopicName}
-			newCaption := b.formatCaption(articleToFormat, newSummary, sourceToFormat, chatCfg)
+			newCaption := b.formatCaption(articleToFormat, summaryFromPending(pendingArticle), sourceToFormat, chatCfg)
 			moderationText := fmt.Sprintf("%s\n\n%s", b.localizer.GetMessage(lang, "approval_header_edited"), newCaption)
 			keyboard := tgbotapi.NewInlineKeyboardMarkup(
 				tgbotapi.NewInlineKeyboardRow(
@@ -137,8 +215,25 @@ func (b *TelegramBot) handleStatefulMessage(message *tgbotapi.Message) {
 		}
 
 	case StateAwaitingSourceURL:
-		state.PendingSource.URL = message.Text
-		if state.PendingSource.Type == "rss" {
+		sourceURL := message.Text
+		if state.PendingSource.Type == "email" {
+			// The URL may carry mailbox credentials, so don't leave it in the chat history.
+			b.api.Request(tgbotapi.NewDeleteMessage(chatID, message.MessageID))
+			if err := news_fetcher.ValidateNewsletterURL(message.Text); err != nil {
+				msg.Text = fmt.Sprintf(b.localizer.GetMessage(lang, "invalid_newsletter_url"), html.EscapeString(err.Error()))
+				msg.ParseMode = tgbotapi.ModeHTML
+				break
+			}
+			sealed, err := news_fetcher.SealNewsletterURL(sourceURL, b.keyBox)
+			if err != nil {
+				msg.Text = fmt.Sprintf(b.localizer.GetMessage(lang, "invalid_newsletter_url"), html.EscapeString(err.Error()))
+				msg.ParseMode = tgbotapi.ModeHTML
+				break
+			}
+			sourceURL = sealed
+		}
+		state.PendingSource.URL = sourceURL
+		if state.PendingSource.Type != "scrape" {
 			b.sendTopicSelectionMenu(chatID, 0, userID)
 			state.Step = StateAwaitingTopicSelection
 			b.setUserState(userID, state)
@@ -152,6 +247,41 @@ func (b *TelegramBot) handleStatefulMessage(message *tgbotapi.Message) {
 		b.sendTopicSelectionMenu(chatID, 0, userID)
 		state.Step = StateAwaitingTopicSelection
 		b.setUserState(userID, state)
+	case StateAwaitingFilterPattern:
+		rule := state.PendingFilter
+		rule.Pattern = message.Text
+		if err := filter.Validate(rule); err != nil {
+			msg.Text = fmt.Sprintf(b.localizer.GetMessage(lang, "invalid_filter_pattern"), err)
+			break
+		}
+		if _, err := b.storage.AddFilterRule(rule); err != nil {
+			log.Printf("Failed to add filter rule for chat %d: %v", chatID, err)
+			msg.Text = b.localizer.GetMessage(lang, "filter_add_failed")
+			b.clearUserState(userID)
+			break
+		}
+		b.clearUserState(userID)
+		b.sendFiltersMenu(chatID, 0)
+	case StateAwaitingSourceLanguages:
+		languages, err := parseLanguageList(message.Text)
+		if err != nil {
+			msg.Text = fmt.Sprintf(b.localizer.GetMessage(lang, "invalid_source_languages"), err)
+			break
+		}
+		if err := b.storage.UpdateChatConfig(chatID, "source_languages", languages); err != nil {
+			log.Printf("Failed to update source_languages for chat %d: %v", chatID, err)
+		}
+		b.clearUserState(userID)
+		b.sendLanguagePolicyMenu(chatID, 0)
+	case StateAwaitingCategoryPattern:
+		if _, pattern := parseCategoryPattern(message.Text); pattern == "" {
+			msg.Text = b.localizer.GetMessage(lang, "catmap_invalid_pattern")
+			break
+		}
+		state.PendingPattern = message.Text
+		state.Step = StateAwaitingCategoryTopic
+		b.setUserState(userID, state)
+		b.sendCategoryTopicMenu(chatID, userID)
 	case StateAwaitingTopicName:
 		topicName := message.Text
 		if err := b.storage.AddTopic(chatID, topicName); err != nil {
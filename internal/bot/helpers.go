@@ -73,6 +73,13 @@ func (b *TelegramBot) setUserState(userID int64, state *ConversationState) {
 	b.userStates[userID] = state
 }
 
+func (b *TelegramBot) getUserState(userID int64) (*ConversationState, bool) {
+	b.stateMutex.Lock()
+	defer b.stateMutex.Unlock()
+	state, ok := b.userStates[userID]
+	return state, ok
+}
+
 func (b *TelegramBot) clearUserState(userID int64) {
 	b.stateMutex.Lock()
 	defer b.stateMutex.Unlock()
@@ -0,0 +1,240 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"news-bot/config"
+	"news-bot/internal/ai"
+	"news-bot/internal/langdetect"
+	"news-bot/internal/news_fetcher"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// isLanguageAllowed applies the chat's source language policy. Articles whose
+// language could not be detected are always allowed.
+func isLanguageAllowed(chatCfg *config.Config, language string) bool {
+	if language == "" {
+		return true
+	}
+	listed := false
+	for _, code := range splitLanguageList(chatCfg.SourceLanguages) {
+		if code == language {
+			listed = true
+			break
+		}
+	}
+	switch chatCfg.SourceLanguageMode {
+	case config.LanguageModeAllow:
+		return listed
+	case config.LanguageModeDeny:
+		return !listed
+	}
+	return true
+}
+
+// summaryLanguage returns the code of the language summaries are written in,
+// or an empty string to keep each article's own language.
+func summaryLanguage(chatCfg *config.Config) string {
+	switch chatCfg.SummaryLanguage {
+	case config.SummaryLanguageSource:
+		return ""
+	case "":
+		return chatCfg.LanguageCode
+	}
+	return chatCfg.SummaryLanguage
+}
+
+// summaryRequest prepares the summarizer input for an article in line with
+// the chat's summary language settings.
+func summaryRequest(chatCfg *config.Config, article *news_fetcher.Article, source news_fetcher.Source) ai.SummaryRequest {
+	req := ai.SummaryRequest{
+		Text:      article.TextContent,
+		Title:     article.Title,
+		URL:       news_fetcher.CanonicalURL(article.Link),
+		Variables: promptVariables(article, source),
+	}
+	req.Variables.Lang = "the original language of the article"
+	if article.Language != "" {
+		req.Variables.Lang = langdetect.Name(article.Language)
+	}
+	if target := summaryLanguage(chatCfg); target != "" {
+		req.TargetLanguage = langdetect.Name(target)
+		req.TranslateTitle = chatCfg.TranslateTitle && article.Language != target
+		req.Variables.Lang = req.TargetLanguage
+	}
+	return req
+}
+
+func splitLanguageList(list string) []string {
+	return strings.FieldsFunc(strings.ToLower(list), func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// parseLanguageList validates a list of language codes sent by the user and
+// returns it in its stored form.
+func parseLanguageList(text string) (string, error) {
+	codes := splitLanguageList(text)
+	seen := make(map[string]bool)
+	var valid []string
+	for _, code := range codes {
+		if !langdetect.IsSupported(code) {
+			return "", fmt.Errorf("unknown language code '%s'", code)
+		}
+		if !seen[code] {
+			seen[code] = true
+			valid = append(valid, code)
+		}
+	}
+	sort.Strings(valid)
+	return strings.Join(valid, ","), nil
+}
+
+func (b *TelegramBot) describeLanguagePolicy(lang string, cfg *config.Config) string {
+	if cfg.SourceLanguageMode != config.LanguageModeAllow && cfg.SourceLanguageMode != config.LanguageModeDeny {
+		return b.localizer.GetMessage(lang, "lang_mode_all")
+	}
+	languages := cfg.SourceLanguages
+	if languages == "" {
+		languages = "-"
+	}
+	return fmt.Sprintf("%s: %s", b.localizer.GetMessage(lang, "lang_mode_"+cfg.SourceLanguageMode), strings.ToUpper(strings.ReplaceAll(languages, ",", ", ")))
+}
+
+func (b *TelegramBot) sendLanguagePolicyMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for language menu for chat %d: %v", chatID, err)
+		return
+	}
+
+	translateStatus := b.localizer.GetMessage(lang, "status_off")
+	if cfg.TranslateTitle {
+		translateStatus = b.localizer.GetMessage(lang, "status_on")
+	}
+	summaryLang := b.describeSummaryLanguage(lang, cfg)
+	text := fmt.Sprintf(b.localizer.GetMessage(lang, "lang_policy_menu_title"), b.describeLanguagePolicy(lang, cfg), summaryLang, translateStatus)
+
+	var modeButtons []tgbotapi.InlineKeyboardButton
+	for _, mode := range []string{config.LanguageModeAll, config.LanguageModeAllow, config.LanguageModeDeny} {
+		label := b.localizer.GetMessage(lang, "lang_mode_"+mode)
+		if cfg.SourceLanguageMode == mode {
+			label = "✅ " + label
+		}
+		modeButtons = append(modeButtons, tgbotapi.NewInlineKeyboardButtonData(label, "lang_mode:"+mode))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		modeButtons,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_edit_source_languages"), "edit_source_languages"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(b.localizer.GetMessage(lang, "btn_summary_language"), summaryLang), "summary_language_menu"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(b.localizer.GetMessage(lang, "btn_toggle_translate_title"), translateStatus), "toggle_translate_title"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_main_settings"), "back_to_settings"),
+		),
+	)
+	b.sendOrEditMenu(chatID, messageID, text, keyboard)
+}
+
+func (b *TelegramBot) handleLanguagePolicyCallback(callback *tgbotapi.CallbackQuery, action string, data string) {
+	userID := callback.From.ID
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	lang := b.getLangForChat(chatID)
+
+	switch action {
+	case "lang_mode":
+		if data != config.LanguageModeAll && data != config.LanguageModeAllow && data != config.LanguageModeDeny {
+			return
+		}
+		if err := b.storage.UpdateChatConfig(chatID, "source_language_mode", data); err != nil {
+			log.Printf("Failed to update source_language_mode for chat %d: %v", chatID, err)
+		}
+	case "toggle_translate_title":
+		cfg, err := b.storage.GetChatConfig(chatID)
+		if err != nil {
+			log.Printf("Failed to get chat config for chat %d: %v", chatID, err)
+			return
+		}
+		if err := b.storage.UpdateChatConfig(chatID, "translate_title", !cfg.TranslateTitle); err != nil {
+			log.Printf("Failed to update translate_title for chat %d: %v", chatID, err)
+		}
+	case "summary_language_menu":
+		b.sendSummaryLanguageMenu(chatID, messageID)
+		return
+	case "set_summary_language":
+		value := data
+		if value == "chat" {
+			value = ""
+		} else if value != config.SummaryLanguageSource && !langdetect.IsSupported(value) {
+			return
+		}
+		if err := b.storage.UpdateChatConfig(chatID, "summary_language", value); err != nil {
+			log.Printf("Failed to update summary_language for chat %d: %v", chatID, err)
+		}
+	case "edit_source_languages":
+		b.setUserState(userID, &ConversationState{Step: StateAwaitingSourceLanguages})
+		var codes []string
+		for _, code := range langdetect.SupportedCodes() {
+			codes = append(codes, fmt.Sprintf("<code>%s</code> %s", code, langdetect.Name(code)))
+		}
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf(b.localizer.GetMessage(lang, "ask_source_languages"), strings.Join(codes, ", ")))
+		editMsg.ParseMode = tgbotapi.ModeHTML
+		b.api.Send(editMsg)
+		return
+	}
+	b.sendLanguagePolicyMenu(chatID, messageID)
+}
+
+func (b *TelegramBot) describeSummaryLanguage(lang string, cfg *config.Config) string {
+	switch cfg.SummaryLanguage {
+	case config.SummaryLanguageSource:
+		return b.localizer.GetMessage(lang, "summary_language_source")
+	case "":
+		return fmt.Sprintf(b.localizer.GetMessage(lang, "summary_language_chat"), langdetect.Name(cfg.LanguageCode))
+	}
+	return langdetect.Name(cfg.SummaryLanguage)
+}
+
+func (b *TelegramBot) sendSummaryLanguageMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for summary language menu for chat %d: %v", chatID, err)
+		return
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf(b.localizer.GetMessage(lang, "summary_language_chat"), langdetect.Name(cfg.LanguageCode)), "set_summary_language:chat")),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			b.localizer.GetMessage(lang, "summary_language_source"), "set_summary_language:"+config.SummaryLanguageSource)),
+	}
+	var row []tgbotapi.InlineKeyboardButton
+	for _, code := range langdetect.SupportedCodes() {
+		label := langdetect.Name(code)
+		if cfg.SummaryLanguage == code {
+			label = "✅ " + label
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, "set_summary_language:"+code))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_menu"), "manage_languages"),
+	))
+	b.sendOrEditMenu(chatID, messageID, b.localizer.GetMessage(lang, "ask_summary_language"), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
@@ -0,0 +1,193 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"news-bot/config"
+	"news-bot/internal/ai"
+	"news-bot/internal/storage"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	modelCatalogTTL  = time.Hour
+	modelListTimeout = 20 * time.Second
+	modelTestTimeout = 45 * time.Second
+	// maxModelButtons keeps the model menu within Telegram's keyboard limits.
+	maxModelButtons = 40
+)
+
+// availableModels returns the models a chat can choose for a provider: the
+// superadmin's allow-list if there is one, otherwise the provider's catalogue
+// as seen with the chat's own API key, or the operator's if it has none.
+func (b *TelegramBot) availableModels(chatID int64, chatCfg *config.Config, provider string) ([]ai.Model, error) {
+	allowed, err := b.storage.GetAllowedModels(provider)
+	if err != nil {
+		return nil, err
+	}
+	if len(allowed) > 0 {
+		models := make([]ai.Model, 0, len(allowed))
+		for _, m := range allowed {
+			name := m.Alias
+			if name == "" {
+				name = m.Model
+			}
+			models = append(models, ai.Model{ID: m.Model, DisplayName: name})
+		}
+		return models, nil
+	}
+
+	providerCfg := b.chatProviderConfig(chatCfg, provider)
+	if !providerCfg.OwnKey && b.byokRequired(chatID) {
+		return nil, fmt.Errorf("the chat needs its own %s API key", provider)
+	}
+	ctx, cancel := context.WithTimeout(b.ctx, modelListTimeout)
+	defer cancel()
+	return b.modelCatalog.Models(ctx, provider, providerCfg, keyFingerprint(providerCfg))
+}
+
+// resolveModel turns a model name or alias typed by a user into a model ID.
+// It fails if the provider has an allow-list that doesn't contain the model.
+func (b *TelegramBot) resolveModel(provider string, name string) (string, bool) {
+	allowed, err := b.storage.GetAllowedModels(provider)
+	if err != nil {
+		log.Printf("Could not load allowed models for %s: %v", provider, err)
+		return name, true
+	}
+	if len(allowed) == 0 {
+		return name, true
+	}
+	for _, m := range allowed {
+		if m.Model == name || (m.Alias != "" && strings.EqualFold(m.Alias, name)) {
+			return m.Model, true
+		}
+	}
+	return "", false
+}
+
+// testModel checks with a small request that a model can be used, with the
+// chat's own API key if it has one for the provider.
+func (b *TelegramBot) testModel(chatID int64, provider string, model string) error {
+	providerCfg := b.providerConfig(provider)
+	if chatCfg, err := b.storage.GetChatConfig(chatID); err == nil {
+		providerCfg = b.chatProviderConfig(chatCfg, provider)
+	}
+	ctx, cancel := context.WithTimeout(b.usageContext(b.ctx, chatID), modelTestTimeout)
+	defer cancel()
+	if err := ai.TestModel(ctx, provider, providerCfg, model); err != nil {
+		log.Printf("Test call to %s model %s for chat %d failed: %v", provider, model, chatID, err)
+		return err
+	}
+	return nil
+}
+
+// applyModelChoice tests the model with a small request and saves it for the
+// chat only if the request succeeds. It returns the message to show.
+func (b *TelegramBot) applyModelChoice(chatID int64, provider string, model string) (string, bool) {
+	lang := b.getLangForChat(chatID)
+	if _, ok := b.resolveModel(provider, model); !ok {
+		return fmt.Sprintf(b.localizer.GetMessage(lang, "model_not_allowed"), html.EscapeString(model)), false
+	}
+
+	if err := b.testModel(chatID, provider, model); err != nil {
+		return fmt.Sprintf(b.localizer.GetMessage(lang, "model_test_failed"), html.EscapeString(model), html.EscapeString(err.Error())), false
+	}
+
+	if err := b.storage.UpdateChatConfig(chatID, "ai_provider", provider); err != nil {
+		log.Printf("Failed to update ai_provider for chat %d: %v", chatID, err)
+		return b.localizer.GetMessage(lang, "settings_error"), false
+	}
+	if err := b.storage.UpdateChatConfig(chatID, "ai_model", model); err != nil {
+		log.Printf("Failed to update ai_model for chat %d: %v", chatID, err)
+		return b.localizer.GetMessage(lang, "settings_error"), false
+	}
+	return b.localizer.GetMessage(lang, "setting_updated_success"), true
+}
+
+// handleAllowModelCommand adds a model to the allow-list of its provider,
+// optionally with an alias: /allow_model <provider> <model> [alias].
+func (b *TelegramBot) handleAllowModelCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := b.getLangForChat(chatID)
+	parts := strings.Fields(message.CommandArguments())
+	if len(parts) < 2 || !isKnownProvider(parts[0]) {
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "allow_model_usage"))
+		return
+	}
+
+	entry := storage.AllowedModel{Provider: parts[0], Model: parts[1], Alias: strings.Join(parts[2:], " ")}
+	if err := b.storage.AddAllowedModel(entry); err != nil {
+		log.Printf("Failed to add allowed model %s/%s: %v", entry.Provider, entry.Model, err)
+		return
+	}
+	b.sendModelCommandReply(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, "allow_model_success"), html.EscapeString(entry.Provider), html.EscapeString(entry.Model)))
+}
+
+// handleRemoveModelCommand removes a model from the allow-list:
+// /remove_model <provider> <model>.
+func (b *TelegramBot) handleRemoveModelCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := b.getLangForChat(chatID)
+	parts := strings.Fields(message.CommandArguments())
+	if len(parts) != 2 {
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "remove_model_usage"))
+		return
+	}
+
+	removed, err := b.storage.RemoveAllowedModel(parts[0], parts[1])
+	if err != nil {
+		log.Printf("Failed to remove allowed model %s/%s: %v", parts[0], parts[1], err)
+		return
+	}
+	key := "remove_model_success"
+	if !removed {
+		key = "remove_model_not_found"
+	}
+	b.sendModelCommandReply(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, key), html.EscapeString(parts[0]), html.EscapeString(parts[1])))
+}
+
+// handleModelsCommand lists the allow-list of every provider.
+func (b *TelegramBot) handleModelsCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := b.getLangForChat(chatID)
+	allowed, err := b.storage.GetAllowedModels("")
+	if err != nil {
+		log.Printf("Failed to list allowed models: %v", err)
+		return
+	}
+	if len(allowed) == 0 {
+		b.sendModelCommandReply(chatID, b.localizer.GetMessage(lang, "allowed_models_empty"))
+		return
+	}
+
+	var builder strings.Builder
+	builder.WriteString(b.localizer.GetMessage(lang, "allowed_models_title") + "\n\n")
+	for _, m := range allowed {
+		line := fmt.Sprintf("• %s / <code>%s</code>", html.EscapeString(m.Provider), html.EscapeString(m.Model))
+		if m.Alias != "" {
+			line += fmt.Sprintf(" (%s)", html.EscapeString(m.Alias))
+		}
+		builder.WriteString(line + "\n")
+	}
+	b.sendModelCommandReply(chatID, builder.String())
+}
+
+func (b *TelegramBot) sendModelCommandReply(chatID int64, text string) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	b.api.Send(msg)
+}
+
+func isKnownProvider(provider string) bool {
+	for _, p := range ai.Providers() {
+		if p == provider {
+			return true
+		}
+	}
+	return false
+}
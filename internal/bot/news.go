@@ -4,11 +4,18 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"html"
 	"log"
+	"math/rand"
 	"net/url"
 	"news-bot/config"
+	"news-bot/internal/ai"
+	"news-bot/internal/filter"
+	"news-bot/internal/langdetect"
 	"news-bot/internal/news_fetcher"
 	"news-bot/internal/storage"
+	"news-bot/internal/telegramhtml"
+	"regexp"
 	"strings"
 	"time"
 
@@ -21,6 +28,10 @@ func (b *TelegramBot) scheduleNewsDispatcher() {
 	b.scheduler.AddJob(newsFetchingJobTag, interval, b.dispatchScheduledFetches)
 }
 
+// pausedFetchSpread is the window over which chats that skipped a fetch
+// because their AI provider was paused fetch again once it resumes.
+const pausedFetchSpread = 10 * time.Minute
+
 func (b *TelegramBot) dispatchScheduledFetches() {
 	allConfigs, err := b.storage.GetAllChatConfigs()
 	if err != nil {
@@ -92,6 +103,41 @@ func (b *TelegramBot) fetchNewsForChat(parentCtx context.Context, chatID int64,
 		log.Printf("[Chat %d] Could not get config, aborting fetch. Error: %v", chatID, err)
 		return
 	}
+	ownKeysOnly := b.byokRequired(chatID)
+	if ownKeysOnly && !b.chatProviderConfig(chatCfg, chatProvider(chatCfg)).OwnKey {
+		log.Printf("[Chat %d] Chat is beyond the free tier and has no API key of its own for %s, skipping fetch.", chatID, chatProvider(chatCfg))
+		if manual {
+			lang := b.getLangForChat(chatID)
+			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, "byok_required"), chatProvider(chatCfg), chatID))
+			msg.ParseMode = tgbotapi.ModeHTML
+			b.api.Send(msg)
+		}
+		return
+	}
+	if until, paused := b.allProvidersPaused(chatCfg); paused {
+		log.Printf("[Chat %d] AI provider %s is paused until %s, skipping fetch.", chatID, chatProvider(chatCfg), until.Format(time.RFC3339))
+		if manual {
+			lang := b.getLangForChat(chatID)
+			b.api.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, "ai_provider_paused"), chatProvider(chatCfg), until.Format("15:04 MST"))))
+		} else {
+			// Fetch again once the pause is over, spread out so that the chats
+			// waiting for the provider don't all fetch at the same moment.
+			resume := until.Add(time.Duration(rand.Int63n(int64(pausedFetchSpread))))
+			interval := time.Duration(chatCfg.ScheduleIntervalMinutes) * time.Minute
+			if err := b.storage.UpdateLastFetchedTime(chatID, resume.Add(-interval)); err != nil {
+				log.Printf("[Chat %d] Failed to postpone the next fetch: %v", chatID, err)
+			}
+		}
+		return
+	}
+	if used, exceeded := b.monthlyQuotaExceeded(chatID, chatCfg.MonthlyTokenQuota); exceeded {
+		log.Printf("[Chat %d] Monthly AI token quota of %d reached (%d used), skipping fetch.", chatID, chatCfg.MonthlyTokenQuota, used)
+		if manual {
+			lang := b.getLangForChat(chatID)
+			b.api.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf(b.localizer.GetMessage(lang, "usage_quota_reached"), used, chatCfg.MonthlyTokenQuota)))
+		}
+		return
+	}
 
 	sources, err := b.storage.GetNewsSourcesForChat(chatID)
 	if err != nil {
@@ -115,6 +161,23 @@ func (b *TelegramBot) fetchNewsForChat(parentCtx context.Context, chatID int64,
 	}
 	log.Printf("[Chat %d] Discovered %d total article links.", chatID, len(discoveredArticles))
 
+	filterRules, err := b.storage.GetFilterRulesForChat(chatID)
+	if err != nil {
+		log.Printf("[Chat %d] Could not load filter rules, continuing without them: %v", chatID, err)
+	}
+	categoryMappings, err := b.storage.GetCategoryMappingsForChat(chatID)
+	if err != nil {
+		log.Printf("[Chat %d] Could not load category mappings, continuing without them: %v", chatID, err)
+	}
+
+	digestTopics := b.digestTopicIDs(chatID)
+	var classificationTopics []storage.Topic
+	if chatCfg.TopicClassification {
+		if classificationTopics, err = b.storage.GetTopicsForChat(chatID); err != nil {
+			log.Printf("[Chat %d] Could not load topics for classification, continuing without it: %v", chatID, err)
+		}
+	}
+
 	postedCount := 0
 	for _, articleStub := range discoveredArticles {
 		select {
@@ -134,31 +197,101 @@ func (b *TelegramBot) fetchNewsForChat(parentCtx context.Context, chatID int64,
 			continue
 		}
 
-		log.Printf("[Chat %d] Found new article: %s. Scraping...", chatID, articleStub.Link)
-		fullArticle, err := b.fetcher.ScrapeArticleDetails(articleStub.Link)
-		if err != nil {
-			log.Printf("[Chat %d] Could not scrape article '%s': %v", chatID, articleStub.Link, err)
-			b.storage.MarkAsPosted(articleStub.Link, chatID)
+		routedByCategory := routeByCategory(&articleStub, categoryMappings)
+		if b.isBlockedByFilters(chatID, filterRules, candidateFromStub(articleStub), filter.StageDiscovery) {
 			continue
 		}
+
+		fullArticle := articleStub.Article
+		if fullArticle != nil {
+			log.Printf("[Chat %d] Found new %s item: %s. Using feed show notes.", chatID, fullArticle.MediaType, articleStub.Link)
+		} else {
+			log.Printf("[Chat %d] Found new article: %s. Scraping...", chatID, articleStub.Link)
+			fullArticle, err = b.fetcher.ScrapeArticleDetails(articleStub.Link)
+			if err != nil {
+				log.Printf("[Chat %d] Could not scrape article '%s': %v", chatID, articleStub.Link, err)
+				b.storage.MarkAsPosted(articleStub.Link, chatID)
+				continue
+			}
+		}
 		fullArticle.PublicationTime = articleStub.PubDate
 
-		summarizer, err := b.getSummarizerForChat(chatCfg)
+		if b.isBlockedByFilters(chatID, filterRules, candidateFromArticle(articleStub, fullArticle), filter.StageFullText) {
+			continue
+		}
+
+		fullArticle.Language = langdetect.Detect(fullArticle.Title + "\n" + fullArticle.TextContent)
+		if !isLanguageAllowed(chatCfg, fullArticle.Language) {
+			log.Printf("[Chat %d] Skipping article '%s' in language '%s' due to the source language policy.", chatID, fullArticle.Link, fullArticle.Language)
+			b.storage.RecordPostedArticle(fullArticle.Link, chatID, fullArticle.Language, "")
+			continue
+		}
+
+		if used, exceeded := b.monthlyQuotaExceeded(chatID, chatCfg.MonthlyTokenQuota); exceeded {
+			log.Printf("[Chat %d] Monthly AI token quota of %d reached (%d used), stopping this run.", chatID, chatCfg.MonthlyTokenQuota, used)
+			break
+		}
+
+		summarizer, err := b.getSummarizerForChat(chatCfg, ownKeysOnly)
 		if err != nil {
 			log.Printf("[Chat %d] Could not get summarizer: %v", chatID, err)
 			continue
 		}
 
-		summary, err := summarizer.Summarize(ctx, fullArticle.TextContent)
+		if chatCfg.InterestProfile != "" && !b.isRelevant(ctx, chatCfg, summarizer, fullArticle, articleStub.Source) {
+			b.storage.RecordPostedArticle(fullArticle.Link, chatID, fullArticle.Language, "")
+			continue
+		}
+
+		// Explicit category mappings take precedence over the model's choice.
+		moderationNote := ""
+		if !routedByCategory && len(classificationTopics) > 0 {
+			moderationNote = b.classifyArticleTopic(ctx, chatCfg, summarizer, classificationTopics, &articleStub.Source, fullArticle)
+		}
+
+		request := summaryRequest(chatCfg, fullArticle, articleStub.Source)
+		request.Style = b.summaryStyle(chatCfg, fullArticle, articleStub.Source)
+		summary, err := summarizer.Summarize(b.usageContext(ctx, chatID), request)
 		if err != nil {
-			if !errors.Is(err, context.Canceled) {
-				log.Printf("[Chat %d] Could not summarize article '%s': %v", chatID, fullArticle.Title, err)
+			if errors.Is(err, context.Canceled) {
+				continue
+			}
+			if blocked, ok := ai.IsBlocked(err); ok {
+				if b.handleBlockedArticle(fullArticle, articleStub.Source, chatCfg, blocked, chatCfg.DigestEnabled || digestTopics[articleStub.Source.TopicID]) {
+					postedCount++
+				}
+				continue
+			}
+			log.Printf("[Chat %d] Could not summarize article '%s': %v", chatID, fullArticle.Title, err)
+			// The remaining articles would hit the same limit, so leave them for the next cycle.
+			if kind, _ := ai.Classify(err); kind == ai.ErrorRateLimited || kind == ai.ErrorQuotaExhausted {
+				log.Printf("[Chat %d] AI provider is %s, stopping this run.", chatID, kind)
+				break
 			}
 			continue
 		}
+		if chatCfg.SummaryVerification != config.VerificationOff && summary.Provider != ai.ProviderExtractive {
+			summary.Unsupported = b.verifySummary(ctx, chatCfg, summarizer, summary, fullArticle, chatID)
+			if len(summary.Unsupported) > 0 {
+				log.Printf("[Chat %d] %d claims in the summary of '%s' are not supported by the article, sending it to moderation.", chatID, len(summary.Unsupported), fullArticle.Title)
+				moderationNote = strings.TrimSpace(moderationNote + "\n" + b.verificationNote(chatID, summary.Unsupported))
+			}
+		}
+		b.fitSummaryToCaption(chatCfg, fullArticle, summary, articleStub.Source)
+		formatSummaryHTML(summary)
 
-		if chatCfg.EnableApprovalSystem {
-			err = b.sendArticleToModeration(fullArticle, summary, articleStub.Source, chatCfg)
+		if moderationNote == "" && (chatCfg.DigestEnabled || digestTopics[articleStub.Source.TopicID]) {
+			if err := b.queueForDigest(chatCfg, fullArticle, summary, articleStub.Source); err != nil {
+				log.Printf("[Chat %d] Failed to queue article '%s' for the digest: %v", chatID, fullArticle.Title, err)
+				continue
+			}
+			b.storage.RecordPostedArticle(fullArticle.Link, chatID, fullArticle.Language, summary.ModelName())
+			postedCount++
+			continue
+		}
+
+		if chatCfg.EnableApprovalSystem || moderationNote != "" {
+			err = b.sendArticleToModeration(fullArticle, summary, articleStub.Source, chatCfg, moderationNote)
 			if err != nil {
 				log.Printf("[Chat %d] Failed to send article to moderation '%s': %v", chatID, fullArticle.Title, err)
 				continue
@@ -169,7 +302,7 @@ func (b *TelegramBot) fetchNewsForChat(parentCtx context.Context, chatID int64,
 				log.Printf("[Chat %d] Failed to send article '%s', it will be retried next cycle: %v", chatID, fullArticle.Title, err)
 				continue
 			}
-			b.storage.MarkAsPosted(fullArticle.Link, chatID)
+			b.storage.RecordPostedArticle(fullArticle.Link, chatID, fullArticle.Language, summary.ModelName())
 		}
 		postedCount++
 
@@ -187,7 +320,7 @@ func (b *TelegramBot) fetchNewsForChat(parentCtx context.Context, chatID int64,
 	}
 }
 
-func (b *TelegramBot) sendArticleToChannel(article *news_fetcher.Article, summary string, source news_fetcher.Source, chatCfg *config.Config) error {
+func (b *TelegramBot) sendArticleToChannel(article *news_fetcher.Article, summary *ai.Summary, source news_fetcher.Source, chatCfg *config.Config) error {
 	caption := b.formatCaption(article, summary, source, chatCfg)
 
 	chatID := source.DestinationChatID
@@ -197,15 +330,35 @@ func (b *TelegramBot) sendArticleToChannel(article *news_fetcher.Article, summar
 		chatID = source.ChatID
 	}
 
-	if article.ImageURL == "" {
-		msg := tgbotapi.NewMessage(chatID, caption)
-		msg.ParseMode = tgbotapi.ModeHTML
-		msg.DisableWebPagePreview = false
-		if replyToID != 0 {
-			msg.ReplyToMessageID = replyToID
+	// A caption over the limit would be rejected, so such articles are posted
+	// as text with the link preview instead of their photo or media.
+	captionTooLong := telegramLength(caption) > telegramCaptionLimit
+	if captionTooLong && postsWithCaption(article) {
+		log.Printf("Caption for chat %d is over %d characters, posting it as text: %s", source.ChatID, telegramCaptionLimit, article.Title)
+	}
+
+	if article.MediaURL != "" {
+		if isAttachableMedia(article) && !captionTooLong {
+			sent, err := b.sendMediaPost(chatID, replyToID, article, caption)
+			if err == nil {
+				log.Printf("Successfully posted %s item to channel for chat %d: %s", article.MediaType, source.ChatID, article.Title)
+				b.recordPostedMessage(source.ChatID, sent, article)
+				return nil
+			}
+			log.Printf("Failed to attach %s for chat %d: %v. Posting it as a link.", article.MediaType, chatID, err)
 		}
-		if _, err := b.api.Send(msg); err != nil {
-			return fmt.Errorf("failed to send text message: %w", err)
+		if !strings.Contains(chatCfg.TelegramMessageTemplate, "{media_link}") {
+			lang := b.getLangForChat(source.ChatID)
+			label := b.localizer.GetMessage(lang, "media_link_"+article.MediaType)
+			caption += fmt.Sprintf("\n\n<a href=\"%s\">%s</a>", html.EscapeString(article.MediaURL), label)
+		}
+	}
+
+	var sent tgbotapi.Message
+	var err error
+	if article.ImageURL == "" || captionTooLong {
+		if sent, err = b.sendTextPost(chatID, replyToID, caption); err != nil {
+			return err
 		}
 	} else {
 		photoMsg := tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(article.ImageURL))
@@ -214,25 +367,100 @@ func (b *TelegramBot) sendArticleToChannel(article *news_fetcher.Article, summar
 		if replyToID != 0 {
 			photoMsg.ReplyToMessageID = replyToID
 		}
-		if _, err := b.api.Send(photoMsg); err != nil {
+		if sent, err = b.api.Send(photoMsg); err != nil {
 			log.Printf("Failed to send photo message for chat %d: %v. Trying as text.", chatID, err)
-			msg := tgbotapi.NewMessage(chatID, caption)
-			msg.ParseMode = tgbotapi.ModeHTML
-			msg.DisableWebPagePreview = false
-			if replyToID != 0 {
-				msg.ReplyToMessageID = replyToID
-			}
-			if _, err_text := b.api.Send(msg); err_text != nil {
+			var err_text error
+			if sent, err_text = b.sendTextPost(chatID, replyToID, caption); err_text != nil {
 				return fmt.Errorf("failed to send message as text either: %w", err_text)
 			}
 		}
 	}
 	log.Printf("Successfully posted article to channel for chat %d: %s", source.ChatID, article.Title)
+	b.recordPostedMessage(source.ChatID, sent, article)
 	return nil
 }
 
-func (b *TelegramBot) formatCaption(article *news_fetcher.Article, summary string, source news_fetcher.Source, chatCfg *config.Config) string {
+func (b *TelegramBot) sendTextPost(chatID int64, replyToID int, text string) (tgbotapi.Message, error) {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.DisableWebPagePreview = false
+	if replyToID != 0 {
+		msg.ReplyToMessageID = replyToID
+	}
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		return sent, fmt.Errorf("failed to send text message: %w", err)
+	}
+	return sent, nil
+}
+
+func (b *TelegramBot) sendMediaPost(chatID int64, replyToID int, article *news_fetcher.Article, caption string) (tgbotapi.Message, error) {
+	var media tgbotapi.Chattable
+	switch article.MediaType {
+	case news_fetcher.MediaTypeAudio:
+		audioMsg := tgbotapi.NewAudio(chatID, tgbotapi.FileURL(article.MediaURL))
+		audioMsg.Caption = caption
+		audioMsg.ParseMode = tgbotapi.ModeHTML
+		audioMsg.Title = article.Title
+		audioMsg.ReplyToMessageID = replyToID
+		media = audioMsg
+	case news_fetcher.MediaTypeVideo:
+		videoMsg := tgbotapi.NewVideo(chatID, tgbotapi.FileURL(article.MediaURL))
+		videoMsg.Caption = caption
+		videoMsg.ParseMode = tgbotapi.ModeHTML
+		videoMsg.SupportsStreaming = true
+		videoMsg.ReplyToMessageID = replyToID
+		media = videoMsg
+	default:
+		return tgbotapi.Message{}, fmt.Errorf("unsupported media type '%s'", article.MediaType)
+	}
+
+	sent, err := b.api.Send(media)
+	if err != nil {
+		return sent, fmt.Errorf("failed to send %s message: %w", article.MediaType, err)
+	}
+	return sent, nil
+}
+
+// isAttachableMedia reports whether Telegram can fetch the enclosure itself.
+// Enclosures of unknown size are tried anyway; the caller falls back to a link.
+func isAttachableMedia(article *news_fetcher.Article) bool {
+	return article.MediaSize <= news_fetcher.MaxAttachableMediaSize
+}
+
+// promptVariables exposes the article details the AI prompt template can use,
+// formatted the same way as in the message template.
+func promptVariables(article *news_fetcher.Article, source news_fetcher.Source) ai.PromptVariables {
+	vars := ai.PromptVariables{
+		Title:       article.Title,
+		Description: article.Description,
+		Link:        article.Link,
+		TopicName:   source.TopicName,
+		PublishDate: "N/A",
+	}
+	if vars.TopicName == "" {
+		vars.TopicName = "General"
+	}
+	if sourceURL, err := url.Parse(source.URL); err == nil {
+		vars.SourceName = strings.TrimPrefix(sourceURL.Hostname(), "www.")
+	}
+	if article.PublicationTime != nil {
+		vars.PublishDate = article.PublicationTime.Format("2 January 2006")
+	}
+	return vars
+}
+
+// linkAnchorPattern matches a template link to the article, which is left out
+// when the article has no web address.
+var linkAnchorPattern = regexp.MustCompile(`(?s)<a\s+href="\{link\}"\s*>.*?</a>`)
+
+func (b *TelegramBot) formatCaption(article *news_fetcher.Article, summary *ai.Summary, source news_fetcher.Source, chatCfg *config.Config) string {
 	template := chatCfg.TelegramMessageTemplate
+	link := article.Link
+	if !news_fetcher.IsWebLink(link) {
+		template = strings.TrimSpace(linkAnchorPattern.ReplaceAllString(template, ""))
+		link = ""
+	}
 
 	topicName := source.TopicName
 	if topicName == "" {
@@ -255,21 +483,125 @@ func (b *TelegramBot) formatCaption(article *news_fetcher.Article, summary strin
 		publishTime = article.PublicationTime.Format("15:04 WIB")
 	}
 
+	duration := "N/A"
+	if article.Duration != "" {
+		duration = article.Duration
+	}
+
+	sourceLang := "N/A"
+	if article.Language != "" {
+		sourceLang = strings.ToUpper(article.Language)
+	}
+
+	title := html.EscapeString(article.Title)
+	aiTitle := summary.Headline
+	if aiTitle == "" {
+		aiTitle = title
+	}
+	titleTranslated := summary.TitleTranslated
+	if titleTranslated == "" {
+		titleTranslated = title
+	}
+	var keyPoints strings.Builder
+	for i, point := range summary.KeyPoints {
+		if i > 0 {
+			keyPoints.WriteString("\n")
+		}
+		keyPoints.WriteString("• " + point)
+	}
+
 	templateReplacer := strings.NewReplacer(
-		"{title}", article.Title,
-		"{summary}", summary,
-		"{link}", article.Link,
-		"{description}", article.Description,
-		"{topic_name}", topicName,
-		"{source_name}", sourceName,
+		"{title}", title,
+		"{summary}", summary.Text,
+		"{ai_title}", aiTitle,
+		"{title_translated}", titleTranslated,
+		"{key_points}", keyPoints.String(),
+		"{hashtags}", strings.Join(summary.Hashtags, " "),
+		"{sentiment}", summary.Sentiment,
+		"{category}", summary.Category,
+		"{link}", html.EscapeString(link),
+		"{description}", html.EscapeString(article.Description),
+		"{topic_name}", html.EscapeString(topicName),
+		"{source_name}", html.EscapeString(sourceName),
 		"{date}", currentDate,
 		"{publish_date}", publishDate,
 		"{publish_time}", publishTime,
+		"{duration}", duration,
+		"{media_link}", html.EscapeString(article.MediaURL),
+		"{source_lang}", sourceLang,
 	)
 	return templateReplacer.Replace(template)
 }
 
-func (b *TelegramBot) sendArticleToModeration(article *news_fetcher.Article, summary string, source news_fetcher.Source, chatCfg *config.Config) error {
+// formatSummaryHTML makes the summary safe to insert into the chat's HTML
+// template. Markdown written by the model is converted into Telegram HTML.
+func formatSummaryHTML(summary *ai.Summary) {
+	if summary.Provider == ai.ProviderExtractive {
+		summary.Text = html.EscapeString(summary.Text)
+		return
+	}
+	summary.Text = telegramhtml.FromMarkdown(summary.Text)
+	summary.Headline = telegramhtml.FromMarkdown(summary.Headline)
+	summary.TitleTranslated = telegramhtml.FromMarkdown(summary.TitleTranslated)
+	for i, point := range summary.KeyPoints {
+		summary.KeyPoints[i] = telegramhtml.FromMarkdown(point)
+	}
+	for i, tag := range summary.Hashtags {
+		summary.Hashtags[i] = html.EscapeString(tag)
+	}
+	summary.Sentiment = html.EscapeString(summary.Sentiment)
+	summary.Category = html.EscapeString(summary.Category)
+}
+
+// handleBlockedArticle applies the chat's policy to an article the AI refused
+// to summarize. With inDigest, an article posted with its description is
+// queued for the digest instead. It reports whether the article was posted or
+// queued.
+func (b *TelegramBot) handleBlockedArticle(article *news_fetcher.Article, source news_fetcher.Source, chatCfg *config.Config, blocked *ai.BlockedError, inDigest bool) bool {
+	chatID := source.ChatID
+	log.Printf("[Chat %d] The AI refused to summarize '%s': %v", chatID, article.Title, blocked)
+
+	fallback := &ai.Summary{Text: html.EscapeString(article.Description)}
+	if chatCfg.BlockedContentPolicy == config.BlockedPolicyDescription && article.Description != "" {
+		if inDigest {
+			if err := b.queueForDigest(chatCfg, article, fallback, source); err != nil {
+				log.Printf("[Chat %d] Failed to queue article '%s' for the digest: %v", chatID, article.Title, err)
+				return false
+			}
+			b.storage.RecordPostedArticle(article.Link, chatID, article.Language, "")
+			return true
+		}
+		if chatCfg.EnableApprovalSystem {
+			if err := b.sendArticleToModeration(article, fallback, source, chatCfg, ""); err != nil {
+				log.Printf("[Chat %d] Failed to send article to moderation '%s': %v", chatID, article.Title, err)
+				return false
+			}
+			return true
+		}
+		if err := b.sendArticleToChannel(article, fallback, source, chatCfg); err != nil {
+			log.Printf("[Chat %d] Failed to send article '%s', it will be retried next cycle: %v", chatID, article.Title, err)
+			return false
+		}
+		b.storage.RecordPostedArticle(article.Link, chatID, article.Language, "")
+		return true
+	}
+
+	lang := b.getLangForChat(chatID)
+	reason := blocked.Reason
+	for _, rating := range blocked.FlaggedRatings() {
+		reason += ", " + rating.String()
+	}
+	note := fmt.Sprintf(b.localizer.GetMessage(lang, "moderation_note_blocked"), html.EscapeString(reason))
+	if err := b.sendArticleToModeration(article, fallback, source, chatCfg, note); err != nil {
+		log.Printf("[Chat %d] Failed to send blocked article to moderation '%s': %v", chatID, article.Title, err)
+		return false
+	}
+	return true
+}
+
+// sendArticleToModeration queues the article for approval. A non-empty note
+// is shown to the moderators above the post.
+func (b *TelegramBot) sendArticleToModeration(article *news_fetcher.Article, summary *ai.Summary, source news_fetcher.Source, chatCfg *config.Config, note string) error {
 	lang := b.getLangForChat(source.ChatID)
 	sourceURL, _ := url.Parse(source.URL)
 	sourceName := strings.TrimPrefix(sourceURL.Hostname(), "www.")
@@ -279,13 +611,26 @@ func (b *TelegramBot) sendArticleToModeration(article *news_fetcher.Article, sum
 	}
 
 	pendingArticle := storage.PendingArticle{
-		ChatID:     source.ChatID,
-		Title:      article.Title,
-		Summary:    summary,
-		Link:       article.Link,
-		ImageURL:   article.ImageURL,
-		TopicName:  topicName,
-		SourceName: sourceName,
+		ChatID:          source.ChatID,
+		Title:           article.Title,
+		Summary:         summary.Text,
+		Link:            article.Link,
+		ImageURL:        article.ImageURL,
+		TopicName:       topicName,
+		SourceName:      sourceName,
+		MediaURL:        article.MediaURL,
+		MediaType:       article.MediaType,
+		MediaSize:       article.MediaSize,
+		Duration:        article.Duration,
+		Language:        article.Language,
+		AITitle:         summary.Headline,
+		KeyPoints:       summary.KeyPoints,
+		Hashtags:        summary.Hashtags,
+		Sentiment:       summary.Sentiment,
+		Category:        summary.Category,
+		TitleTranslated: summary.TitleTranslated,
+		Model:           summary.ModelName(),
+		ArticleText:     article.TextContent,
 	}
 
 	pendingID, err := b.storage.AddPendingArticle(source.ChatID, pendingArticle)
@@ -298,8 +643,11 @@ func (b *TelegramBot) sendArticleToModeration(article *news_fetcher.Article, sum
 		approvalChatID = source.ChatID
 	}
 
-	caption := b.formatCaption(article, summary, source, chatCfg)
+	caption := highlightUnsupported(b.formatCaption(article, summary, source, chatCfg), summary.Unsupported)
 	moderationText := fmt.Sprintf("%s\n\n%s", b.localizer.GetMessage(lang, "approval_header"), caption)
+	if note != "" {
+		moderationText = fmt.Sprintf("%s\n%s\n\n%s", b.localizer.GetMessage(lang, "approval_header"), note, caption)
+	}
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(
 		tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_approve"), fmt.Sprintf("approve_article:%d", pendingID)),
@@ -317,4 +665,17 @@ func (b *TelegramBot) sendArticleToModeration(article *news_fetcher.Article, sum
 	}
 	log.Printf("Article '%s' for chat %d sent for moderation.", article.Title, source.ChatID)
 	return nil
+}
+
+// summaryFromPending restores the AI summary stored with a pending article.
+func summaryFromPending(article *storage.PendingArticle) *ai.Summary {
+	return &ai.Summary{
+		Text:            article.Summary,
+		Headline:        article.AITitle,
+		KeyPoints:       article.KeyPoints,
+		Hashtags:        article.Hashtags,
+		Sentiment:       article.Sentiment,
+		Category:        article.Category,
+		TitleTranslated: article.TitleTranslated,
+	}
 }
\ No newline at end of file
@@ -0,0 +1,252 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"news-bot/internal/ai"
+	"news-bot/internal/storage"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// builtinPresets are the prompt presets every chat can pick from. Their names
+// and prompts are localized as preset_name_<id> and preset_prompt_<id>.
+var builtinPresets = []string{"neutral_brief", "bullet_points", "explain_new", "breaking_alert", "long_analysis"}
+
+const (
+	maxPromptPresets   = 20
+	maxPresetNameRunes = 40
+	// maxShareTargets bounds how many chats are offered when sharing a preset.
+	maxShareTargets = 20
+)
+
+func (b *TelegramBot) sendPromptPresetsMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for presets menu for chat %d: %v", chatID, err)
+		return
+	}
+	presets, err := b.storage.GetPromptPresets(chatID)
+	if err != nil {
+		log.Printf("Failed to get prompt presets for chat %d: %v", chatID, err)
+	}
+
+	text := fmt.Sprintf(b.localizer.GetMessage(lang, "prompt_presets_title"), html.EscapeString(shortenForButton(cfg.AiPrompt, 300)))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < len(builtinPresets); i += 2 {
+		row := tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "preset_name_"+builtinPresets[i]), "use_preset:b:"+builtinPresets[i]),
+		)
+		if i+1 < len(builtinPresets) {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "preset_name_"+builtinPresets[i+1]), "use_preset:b:"+builtinPresets[i+1]))
+		}
+		rows = append(rows, row)
+	}
+	for _, preset := range presets {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⭐ "+shortenForButton(preset.Name, 30), fmt.Sprintf("use_preset:c:%d", preset.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("📤", fmt.Sprintf("share_preset_menu:%d", preset.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("🗑", fmt.Sprintf("delete_preset:%d", preset.ID)),
+		))
+	}
+	if len(presets) < maxPromptPresets {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_save_preset"), "save_preset"),
+		))
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_write_own_prompt"), "edit_ai_prompt"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_main_settings"), "back_to_settings"),
+		),
+	)
+	b.sendOrEditMenu(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// sendSharePresetMenu lists the other chats the user administers as targets
+// for one of this chat's presets.
+func (b *TelegramBot) sendSharePresetMenu(chatID int64, messageID int, userID int64, presetID int64) {
+	lang := b.getLangForChat(chatID)
+	preset, err := b.storage.GetPromptPreset(presetID, chatID)
+	if err != nil {
+		log.Printf("Failed to get prompt preset %d for chat %d: %v", presetID, chatID, err)
+		b.sendPromptPresetsMenu(chatID, messageID)
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, target := range b.adminChats(userID, chatID) {
+		title := target.Title
+		if title == "" {
+			title = strconv.FormatInt(target.ChatID, 10)
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(shortenForButton(title, 40), fmt.Sprintf("share_preset:%d:%d", presetID, target.ChatID)),
+		))
+	}
+	text := fmt.Sprintf(b.localizer.GetMessage(lang, "share_preset_title"), html.EscapeString(preset.Name))
+	if len(rows) == 0 {
+		text = b.localizer.GetMessage(lang, "share_preset_no_chats")
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_menu"), "prompt_presets"),
+	))
+	b.sendOrEditMenu(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+// recordAdminActivity remembers the chat as one the user manages, so that it
+// can be offered when the user shares a preset from another chat.
+func (b *TelegramBot) recordAdminActivity(chat *tgbotapi.Chat, userID int64) {
+	if chat == nil {
+		return
+	}
+	if err := b.storage.RecordAdminActivity(chat.ID, userID, displayTitle(chat)); err != nil {
+		log.Printf("[Chat %d] Failed to record admin activity of user %d: %v", chat.ID, userID, err)
+	}
+}
+
+// adminChats returns the chats other than exceptChatID that the user has
+// managed the bot in. Admin rights are checked again when sharing.
+func (b *TelegramBot) adminChats(userID int64, exceptChatID int64) []storage.AdminChat {
+	candidates, err := b.storage.GetAdminChats(userID, maxShareTargets+1)
+	if err != nil {
+		log.Printf("Failed to load the chats of user %d: %v", userID, err)
+		return nil
+	}
+	var chats []storage.AdminChat
+	for _, chat := range candidates {
+		if chat.ChatID != exceptChatID && len(chats) < maxShareTargets {
+			chats = append(chats, chat)
+		}
+	}
+	return chats
+}
+
+func (b *TelegramBot) chatTitle(chatID int64) string {
+	chat, err := b.api.GetChat(tgbotapi.ChatInfoConfig{ChatConfig: tgbotapi.ChatConfig{ChatID: chatID}})
+	if err != nil {
+		return strconv.FormatInt(chatID, 10)
+	}
+	return displayTitle(&chat)
+}
+
+func displayTitle(chat *tgbotapi.Chat) string {
+	if chat.Title != "" {
+		return chat.Title
+	}
+	return strings.TrimSpace(chat.FirstName + " " + chat.LastName)
+}
+
+func (b *TelegramBot) handlePromptPresetCallback(callback *tgbotapi.CallbackQuery, action string, data string) {
+	userID := callback.From.ID
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	lang := b.getLangForChat(chatID)
+	callbackAns := tgbotapi.NewCallback(callback.ID, "")
+
+	switch action {
+	case "prompt_presets":
+		b.clearUserState(userID)
+	case "use_preset":
+		kind, id, _ := strings.Cut(data, ":")
+		var name, prompt string
+		if kind == "b" {
+			name, prompt = b.localizer.GetMessage(lang, "preset_name_"+id), b.localizer.GetMessage(lang, "preset_prompt_"+id)
+		} else {
+			presetID, _ := strconv.ParseInt(id, 10, 64)
+			preset, err := b.storage.GetPromptPreset(presetID, chatID)
+			if err != nil {
+				log.Printf("Failed to get prompt preset %d for chat %d: %v", presetID, chatID, err)
+				callbackAns.Text = b.localizer.GetMessage(lang, "settings_error")
+				break
+			}
+			name, prompt = preset.Name, preset.Prompt
+		}
+		if err := ai.ValidatePromptTemplate(prompt); err != nil {
+			log.Printf("Prompt preset '%s' is invalid: %v", name, err)
+			callbackAns.Text = fmt.Sprintf(b.localizer.GetMessage(lang, "preset_invalid"), name)
+			callbackAns.ShowAlert = true
+			break
+		}
+		if err := b.storage.UpdateChatConfig(chatID, "ai_prompt", prompt); err != nil {
+			log.Printf("Failed to update ai_prompt for chat %d: %v", chatID, err)
+			callbackAns.Text = b.localizer.GetMessage(lang, "settings_error")
+			break
+		}
+		callbackAns.Text = fmt.Sprintf(b.localizer.GetMessage(lang, "preset_applied"), name)
+	case "delete_preset":
+		presetID, _ := strconv.ParseInt(data, 10, 64)
+		if err := b.storage.DeletePromptPreset(presetID, chatID); err != nil {
+			log.Printf("Failed to delete prompt preset %d for chat %d: %v", presetID, chatID, err)
+		}
+	case "save_preset":
+		b.setUserState(userID, &ConversationState{Step: StateAwaitingPresetName})
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf(b.localizer.GetMessage(lang, "ask_preset_name"), maxPresetNameRunes))
+		editMsg.ParseMode = tgbotapi.ModeHTML
+		b.api.Send(editMsg)
+		return
+	case "share_preset_menu":
+		presetID, _ := strconv.ParseInt(data, 10, 64)
+		b.sendSharePresetMenu(chatID, messageID, userID, presetID)
+		return
+	case "share_preset":
+		presetArg, targetArg, _ := strings.Cut(data, ":")
+		presetID, _ := strconv.ParseInt(presetArg, 10, 64)
+		targetChatID, _ := strconv.ParseInt(targetArg, 10, 64)
+		callbackAns.Text = b.sharePreset(chatID, userID, presetID, targetChatID)
+		callbackAns.ShowAlert = true
+	}
+	b.api.Request(callbackAns)
+	b.sendPromptPresetsMenu(chatID, messageID)
+}
+
+// sharePreset copies one of the chat's presets to another chat the user
+// administers and returns the message to show.
+func (b *TelegramBot) sharePreset(chatID int64, userID int64, presetID int64, targetChatID int64) string {
+	lang := b.getLangForChat(chatID)
+	if !b.isChatAdmin(targetChatID, userID) {
+		return b.localizer.GetMessage(lang, "permission_denied")
+	}
+	preset, err := b.storage.GetPromptPreset(presetID, chatID)
+	if err != nil {
+		log.Printf("Failed to get prompt preset %d for chat %d: %v", presetID, chatID, err)
+		return b.localizer.GetMessage(lang, "settings_error")
+	}
+	targetPresets, err := b.storage.GetPromptPresets(targetChatID)
+	if err != nil || len(targetPresets) >= maxPromptPresets {
+		return fmt.Sprintf(b.localizer.GetMessage(lang, "share_preset_failed"), preset.Name)
+	}
+	if err := b.storage.AddPromptPreset(targetChatID, preset.Name, preset.Prompt); err != nil {
+		log.Printf("Failed to share prompt preset %d from chat %d to chat %d: %v", presetID, chatID, targetChatID, err)
+		return fmt.Sprintf(b.localizer.GetMessage(lang, "share_preset_failed"), preset.Name)
+	}
+	log.Printf("Prompt preset '%s' shared from chat %d to chat %d by user %d.", preset.Name, chatID, targetChatID, userID)
+	return fmt.Sprintf(b.localizer.GetMessage(lang, "share_preset_success"), preset.Name, b.chatTitle(targetChatID))
+}
+
+// savePreset stores the chat's current prompt under the given name and
+// returns the error message to show, if any.
+func (b *TelegramBot) savePreset(chatID int64, name string) string {
+	lang := b.getLangForChat(chatID)
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxPresetNameRunes {
+		return fmt.Sprintf(b.localizer.GetMessage(lang, "invalid_preset_name"), maxPresetNameRunes)
+	}
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for chat %d: %v", chatID, err)
+		return b.localizer.GetMessage(lang, "settings_error")
+	}
+	if err := b.storage.AddPromptPreset(chatID, name, cfg.AiPrompt); err != nil {
+		log.Printf("Failed to save prompt preset for chat %d: %v", chatID, err)
+		return b.localizer.GetMessage(lang, "preset_save_failed")
+	}
+	return ""
+}
@@ -0,0 +1,283 @@
+package bot
+
+import (
+	"context"
+	"log"
+	"news-bot/internal/ai"
+	"news-bot/internal/news_fetcher"
+	"news-bot/internal/storage"
+	"news-bot/internal/telegramhtml"
+	"regexp"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// questionsPerHour is how many questions one user may ask per hour.
+	questionsPerHour = 5
+	questionTimeout  = time.Minute
+	// postedMessageRetention is how long questions about a post are answered.
+	postedMessageRetention = 30 * 24 * time.Hour
+)
+
+func (b *TelegramBot) schedulePostedMessageCleanup() {
+	b.scheduler.AddJob(postedMessageCleanupJobTag, 24*time.Hour, func() {
+		if err := b.storage.DeletePostedMessagesBefore(time.Now().Add(-postedMessageRetention)); err != nil {
+			log.Printf("Failed to clean up posted messages: %v", err)
+		}
+		b.pruneQuestionTimes()
+		b.pruneThreadRoots()
+	})
+}
+
+// recordPostedMessage remembers which article a sent message is about.
+func (b *TelegramBot) recordPostedMessage(chatID int64, sent tgbotapi.Message, article *news_fetcher.Article) {
+	if sent.Chat == nil || sent.MessageID == 0 {
+		return
+	}
+	text := article.TextContent
+	if text == "" {
+		text = article.Description
+	}
+	message := storage.PostedMessage{
+		ChatID:            chatID,
+		DestinationChatID: sent.Chat.ID,
+		MessageID:         sent.MessageID,
+		Link:              article.Link,
+		Title:             article.Title,
+		ArticleText:       text,
+	}
+	if err := b.storage.AddPostedMessage(message); err != nil {
+		log.Printf("[Chat %d] Failed to record posted message %d: %v", chatID, sent.MessageID, err)
+	}
+}
+
+// threadMessage identifies a comment in a channel's discussion group.
+type threadMessage struct {
+	chatID    int64
+	messageID int
+}
+
+// threadRoot is the posted article a comment thread belongs to.
+type threadRoot struct {
+	destinationChatID int64
+	messageID         int
+	seen              time.Time
+}
+
+// questionedArticle returns the posted article a message replies to. Replies
+// to the bot's own posts and answers are matched directly, comments in a
+// channel's discussion group through the automatically forwarded post. A
+// mention of the bot in reply to another comment is matched through the
+// thread that comment belongs to.
+func (b *TelegramBot) questionedArticle(message *tgbotapi.Message) (*storage.PostedMessage, bool) {
+	reply := message.ReplyToMessage
+	if reply == nil {
+		return nil, b.mentionsBot(message)
+	}
+	var posted *storage.PostedMessage
+	var err error
+	addressed := b.mentionsBot(message)
+	switch {
+	case reply.IsAutomaticForward && reply.ForwardFromChat != nil:
+		posted, err = b.storage.GetPostedMessage(reply.ForwardFromChat.ID, reply.ForwardFromMessageID)
+	case reply.From != nil && reply.From.ID == b.api.Self.ID:
+		posted, err = b.storage.GetPostedMessage(message.Chat.ID, reply.MessageID)
+		addressed = true
+	default:
+		root, ok := b.threadRootOf(message.Chat.ID, reply.MessageID)
+		if !ok {
+			return nil, addressed
+		}
+		posted, err = b.storage.GetPostedMessage(root.destinationChatID, root.messageID)
+	}
+	if err != nil {
+		if err != storage.ErrNotFound {
+			log.Printf("Failed to look up the post message %d in chat %d replies to: %v", reply.MessageID, message.Chat.ID, err)
+		}
+		return nil, addressed
+	}
+	b.rememberThread(message.Chat.ID, message.MessageID, posted)
+	return posted, addressed
+}
+
+// rememberThread records which posted article a comment belongs to, so that
+// a later reply to the comment can be matched to the article.
+func (b *TelegramBot) rememberThread(chatID int64, messageID int, posted *storage.PostedMessage) {
+	b.threadMutex.Lock()
+	defer b.threadMutex.Unlock()
+	b.threadRoots[threadMessage{chatID: chatID, messageID: messageID}] = threadRoot{destinationChatID: posted.DestinationChatID, messageID: posted.MessageID, seen: time.Now()}
+}
+
+func (b *TelegramBot) threadRootOf(chatID int64, messageID int) (threadRoot, bool) {
+	b.threadMutex.Lock()
+	defer b.threadMutex.Unlock()
+	root, ok := b.threadRoots[threadMessage{chatID: chatID, messageID: messageID}]
+	return root, ok
+}
+
+// hasThreads reports whether comments on posted articles were seen in chatID.
+func (b *TelegramBot) hasThreads(chatID int64) bool {
+	b.threadMutex.Lock()
+	defer b.threadMutex.Unlock()
+	for message := range b.threadRoots {
+		if message.chatID == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneThreadRoots forgets comments on posts that are too old to be asked
+// about.
+func (b *TelegramBot) pruneThreadRoots() {
+	b.threadMutex.Lock()
+	defer b.threadMutex.Unlock()
+
+	cutoff := time.Now().Add(-postedMessageRetention)
+	for message, root := range b.threadRoots {
+		if root.seen.Before(cutoff) {
+			delete(b.threadRoots, message)
+		}
+	}
+}
+
+func (b *TelegramBot) mentionsBot(message *tgbotapi.Message) bool {
+	return b.api.Self.UserName != "" && strings.Contains(strings.ToLower(message.Text), "@"+strings.ToLower(b.api.Self.UserName))
+}
+
+// allowQuestion applies the per-user rate limit.
+func (b *TelegramBot) allowQuestion(userID int64) bool {
+	b.questionMutex.Lock()
+	defer b.questionMutex.Unlock()
+
+	cutoff := time.Now().Add(-time.Hour)
+	var recent []time.Time
+	for _, asked := range b.questionTimes[userID] {
+		if asked.After(cutoff) {
+			recent = append(recent, asked)
+		}
+	}
+	if len(recent) >= questionsPerHour {
+		b.questionTimes[userID] = recent
+		return false
+	}
+	b.questionTimes[userID] = append(recent, time.Now())
+	return true
+}
+
+// pruneQuestionTimes forgets users who have not asked a question within the
+// rate limit's window.
+func (b *TelegramBot) pruneQuestionTimes() {
+	b.questionMutex.Lock()
+	defer b.questionMutex.Unlock()
+
+	cutoff := time.Now().Add(-time.Hour)
+	for userID, times := range b.questionTimes {
+		if len(times) == 0 || !times[len(times)-1].After(cutoff) {
+			delete(b.questionTimes, userID)
+		}
+	}
+}
+
+// handleArticleQuestion answers a reader's reply to a posted article using the
+// article's text. Comments in a discussion group are only taken up when they
+// mention the bot or look like a question, and unrelated ones are declined
+// only when the bot was addressed directly.
+func (b *TelegramBot) handleArticleQuestion(message *tgbotapi.Message) {
+	if message.From == nil || message.From.IsBot || strings.TrimSpace(message.Text) == "" {
+		return
+	}
+	posted, addressed := b.questionedArticle(message)
+	if posted == nil {
+		if addressed && b.hasThreads(message.Chat.ID) {
+			b.replyToQuestion(message, b.localizer.GetMessage(b.getLangForChat(message.Chat.ID), "qa_reply_to_article"))
+		}
+		return
+	}
+	if !addressed && !strings.Contains(message.Text, "?") {
+		return
+	}
+	chatID := posted.ChatID
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("[Chat %d] Could not get config to answer a question: %v", chatID, err)
+		return
+	}
+	if !cfg.ArticleQAEnabled {
+		return
+	}
+	lang := b.getLangForChat(chatID)
+
+	if !b.allowQuestion(message.From.ID) {
+		if addressed {
+			b.replyToQuestion(message, b.localizer.GetMessage(lang, "qa_rate_limited"))
+		}
+		return
+	}
+	if strings.TrimSpace(posted.ArticleText) == "" {
+		if addressed {
+			b.replyToQuestion(message, b.localizer.GetMessage(lang, "qa_no_article_text"))
+		}
+		return
+	}
+	if _, exceeded := b.monthlyQuotaExceeded(chatID, cfg.MonthlyTokenQuota); exceeded {
+		if addressed {
+			b.replyToQuestion(message, b.localizer.GetMessage(lang, "qa_unavailable"))
+		}
+		return
+	}
+
+	summarizer, err := b.getSummarizerForChat(cfg, b.byokRequired(chatID))
+	if err != nil {
+		log.Printf("[Chat %d] Could not get summarizer to answer a question: %v", chatID, err)
+		return
+	}
+	answerer, ok := summarizer.(ai.Answerer)
+	if !ok {
+		return
+	}
+
+	question := botMentionPattern(b.api.Self.UserName).ReplaceAllString(message.Text, "")
+	ctx, cancel := context.WithTimeout(b.usageContext(b.ctx, chatID), questionTimeout)
+	defer cancel()
+	answer, err := answerer.Answer(ctx, ai.QuestionRequest{Title: posted.Title, ArticleText: posted.ArticleText, Question: strings.TrimSpace(question)})
+	if err != nil {
+		log.Printf("[Chat %d] Could not answer a question about '%s': %v", chatID, posted.Title, err)
+		if addressed {
+			b.replyToQuestion(message, b.localizer.GetMessage(lang, "qa_unavailable"))
+		}
+		return
+	}
+	if !answer.Related {
+		if addressed {
+			b.replyToQuestion(message, b.localizer.GetMessage(lang, "qa_unrelated"))
+		}
+		return
+	}
+
+	sent, err := b.replyToQuestion(message, telegramhtml.FromMarkdown(answer.Text))
+	if err != nil {
+		return
+	}
+	// Follow-up questions can be asked in reply to the answer.
+	b.recordPostedMessage(chatID, sent, &news_fetcher.Article{Title: posted.Title, Link: posted.Link, TextContent: posted.ArticleText})
+}
+
+func (b *TelegramBot) replyToQuestion(message *tgbotapi.Message, text string) (tgbotapi.Message, error) {
+	msg := tgbotapi.NewMessage(message.Chat.ID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.ReplyToMessageID = message.MessageID
+	msg.DisableWebPagePreview = true
+	sent, err := b.api.Send(msg)
+	if err != nil {
+		log.Printf("Failed to reply to a question in chat %d: %v", message.Chat.ID, err)
+	}
+	return sent, err
+}
+
+func botMentionPattern(username string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)@` + regexp.QuoteMeta(username) + `\b`)
+}
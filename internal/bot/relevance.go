@@ -0,0 +1,166 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"news-bot/config"
+	"news-bot/internal/ai"
+	"news-bot/internal/news_fetcher"
+	"news-bot/internal/storage"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// relevanceDropsShown is how many skipped articles the relevance menu
+	// lists, settingsRelevanceDrops how many /settings lists.
+	relevanceDropsShown    = 10
+	settingsRelevanceDrops = 3
+	maxInterestProfile     = 1000
+)
+
+// isRelevant rates the article against the chat's interest profile from its
+// title and lead, before any summary is written. Articles that cannot be
+// rated are let through.
+func (b *TelegramBot) isRelevant(ctx context.Context, chatCfg *config.Config, summarizer ai.Summarizer, article *news_fetcher.Article, source news_fetcher.Source) bool {
+	chatID := source.ChatID
+	scorer, ok := summarizer.(ai.RelevanceScorer)
+	if !ok {
+		return true
+	}
+	lead := article.Description
+	if lead == "" {
+		lead = ai.ExtractiveSummary(article.TextContent)
+	}
+
+	relevance, err := scorer.ScoreRelevance(b.usageContext(ctx, chatID), ai.RelevanceRequest{Title: article.Title, Lead: lead, Profile: chatCfg.InterestProfile})
+	if err != nil {
+		log.Printf("[Chat %d] Could not rate the relevance of '%s', posting it anyway: %v", chatID, article.Title, err)
+		return true
+	}
+	log.Printf("[Chat %d] Relevance of '%s': %d (threshold %d). %s", chatID, article.Title, relevance.Score, chatCfg.RelevanceThreshold, relevance.Reason)
+	if relevance.Score >= chatCfg.RelevanceThreshold {
+		return true
+	}
+
+	drop := storage.RelevanceDrop{Link: article.Link, Title: article.Title, Score: relevance.Score, Reason: relevance.Reason}
+	if err := b.storage.AddRelevanceDrop(chatID, drop); err != nil {
+		log.Printf("[Chat %d] Failed to record the skipped article '%s': %v", chatID, article.Title, err)
+	}
+	return false
+}
+
+// recentRelevanceDrops lists the chat's latest articles skipped as
+// irrelevant, with their scores, or returns an empty string if there are none.
+func (b *TelegramBot) recentRelevanceDrops(chatID int64, lang string, limit int) string {
+	drops, err := b.storage.GetRecentRelevanceDrops(chatID, limit)
+	if err != nil {
+		log.Printf("Failed to get relevance drops for chat %d: %v", chatID, err)
+		return ""
+	}
+	if len(drops) == 0 {
+		return ""
+	}
+	var builder strings.Builder
+	builder.WriteString(b.localizer.GetMessage(lang, "relevance_drops_title"))
+	for _, drop := range drops {
+		builder.WriteString(fmt.Sprintf("• <b>%d</b> <a href=\"%s\">%s</a>", drop.Score, html.EscapeString(drop.Link), html.EscapeString(drop.Title)))
+		if drop.Reason != "" {
+			builder.WriteString(" — <i>" + html.EscapeString(drop.Reason) + "</i>")
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+func (b *TelegramBot) sendRelevanceMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for relevance menu for chat %d: %v", chatID, err)
+		return
+	}
+
+	profile := b.localizer.GetMessage(lang, "relevance_profile_empty")
+	if cfg.InterestProfile != "" {
+		profile = "<i>" + html.EscapeString(cfg.InterestProfile) + "</i>"
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "relevance_menu_title"), profile, cfg.RelevanceThreshold))
+
+	if drops := b.recentRelevanceDrops(chatID, lang, relevanceDropsShown); drops != "" {
+		builder.WriteString(drops)
+	} else if cfg.InterestProfile != "" {
+		builder.WriteString(b.localizer.GetMessage(lang, "relevance_drops_empty"))
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_edit_interest_profile"), "edit_interest_profile"),
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_edit_relevance_threshold"), "edit_relevance_threshold"),
+		),
+	}
+	if cfg.InterestProfile != "" {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_clear_interest_profile"), "clear_interest_profile"),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_main_settings"), "back_to_settings"),
+	))
+	b.sendOrEditMenu(chatID, messageID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+func (b *TelegramBot) handleRelevanceCallback(callback *tgbotapi.CallbackQuery, action string) {
+	userID := callback.From.ID
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	lang := b.getLangForChat(chatID)
+
+	switch action {
+	case "relevance_menu":
+		b.clearUserState(userID)
+	case "clear_interest_profile":
+		if err := b.storage.UpdateChatConfig(chatID, "interest_profile", ""); err != nil {
+			log.Printf("Failed to clear interest_profile for chat %d: %v", chatID, err)
+		}
+	case "edit_interest_profile", "edit_relevance_threshold":
+		step, prompt := StateAwaitingInterestProfile, "ask_interest_profile"
+		if action == "edit_relevance_threshold" {
+			step, prompt = StateAwaitingMinRelevance, "ask_relevance_threshold"
+		}
+		b.setUserState(userID, &ConversationState{Step: step})
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, b.localizer.GetMessage(lang, prompt))
+		editMsg.ParseMode = tgbotapi.ModeHTML
+		b.api.Send(editMsg)
+		return
+	}
+	b.sendRelevanceMenu(chatID, messageID)
+}
+
+// updateRelevanceSetting validates and saves the interest profile or the
+// relevance threshold. It returns the error message to show, if any.
+func (b *TelegramBot) updateRelevanceSetting(chatID int64, step string, text string) string {
+	lang := b.getLangForChat(chatID)
+	text = strings.TrimSpace(text)
+	column, value := "interest_profile", interface{}(text)
+	if step == StateAwaitingMinRelevance {
+		threshold, err := strconv.Atoi(strings.TrimSuffix(text, "%"))
+		if err != nil || threshold < 0 || threshold > 100 {
+			return b.localizer.GetMessage(lang, "invalid_relevance_threshold")
+		}
+		column, value = "relevance_threshold", threshold
+	} else if text == "" || utf8.RuneCountInString(text) > maxInterestProfile {
+		return fmt.Sprintf(b.localizer.GetMessage(lang, "invalid_interest_profile"), maxInterestProfile)
+	}
+	if err := b.storage.UpdateChatConfig(chatID, column, value); err != nil {
+		log.Printf("Failed to update %s for chat %d: %v", column, chatID, err)
+		return b.localizer.GetMessage(lang, "settings_error")
+	}
+	return ""
+}
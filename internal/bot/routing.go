@@ -0,0 +1,191 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"news-bot/internal/news_fetcher"
+	"news-bot/internal/storage"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// routeByCategory moves an article to the topic of the first mapping of its
+// source that matches and reports whether one did. Unmapped articles keep the
+// source's own topic.
+func routeByCategory(stub *news_fetcher.DiscoveredArticle, mappings []storage.CategoryMapping) bool {
+	for _, mapping := range mappings {
+		if mapping.SourceID != stub.Source.ID || !categoryMappingMatches(mapping, stub) {
+			continue
+		}
+		stub.Source.TopicID = mapping.TopicID
+		stub.Source.TopicName = mapping.TopicName
+		stub.Source.DestinationChatID = mapping.DestinationChatID
+		stub.Source.ReplyToMessageID = mapping.ReplyToMessageID
+		return true
+	}
+	return false
+}
+
+func categoryMappingMatches(mapping storage.CategoryMapping, stub *news_fetcher.DiscoveredArticle) bool {
+	switch mapping.MatchType {
+	case storage.MatchTypeCategory:
+		for _, category := range stub.Categories {
+			if strings.EqualFold(strings.TrimSpace(category), mapping.Pattern) {
+				return true
+			}
+		}
+	case storage.MatchTypePath:
+		u, err := url.Parse(stub.Link)
+		if err != nil {
+			return false
+		}
+		for _, segment := range strings.Split(u.Path, "/") {
+			if strings.EqualFold(segment, mapping.Pattern) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseCategoryPattern reads "Sport" as a category and "path:sport" as a URL path segment.
+func parseCategoryPattern(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	if rest, ok := strings.CutPrefix(strings.ToLower(text), "path:"); ok {
+		return storage.MatchTypePath, strings.Trim(strings.TrimSpace(rest), "/")
+	}
+	return storage.MatchTypeCategory, text
+}
+
+func (b *TelegramBot) sendCategoryMappingSourcesMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	sources, err := b.storage.GetNewsSourcesForChat(chatID)
+	if err != nil {
+		log.Printf("Failed to get sources for category mapping menu for chat %d: %v", chatID, err)
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, source := range sources {
+		label := fmt.Sprintf("%s (%s)", shortenForButton(news_fetcher.DisplayURL(source.URL), 35), source.Type)
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("catmap_source:%d", source.ID)),
+		))
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_menu"), "manage_sources"),
+	))
+	b.sendOrEditMenu(chatID, messageID, b.localizer.GetMessage(lang, "catmap_sources_title"), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+func (b *TelegramBot) sendCategoryMappingsMenu(chatID int64, messageID int, sourceID int64) {
+	lang := b.getLangForChat(chatID)
+	mappings, err := b.storage.GetCategoryMappingsForChat(chatID)
+	if err != nil {
+		log.Printf("Failed to get category mappings for chat %d: %v", chatID, err)
+		return
+	}
+
+	var builder strings.Builder
+	builder.WriteString(b.localizer.GetMessage(lang, "catmap_title"))
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, mapping := range mappings {
+		if mapping.SourceID != sourceID {
+			continue
+		}
+		line := fmt.Sprintf(b.localizer.GetMessage(lang, "catmap_format_"+mapping.MatchType), mapping.Pattern, mapping.TopicName)
+		builder.WriteString(line + "\n")
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("❌ "+shortenForButton(mapping.Pattern+" → "+mapping.TopicName, 40), fmt.Sprintf("delete_catmap:%d:%d", sourceID, mapping.ID)),
+		))
+	}
+	if len(rows) == 0 {
+		builder.WriteString(b.localizer.GetMessage(lang, "catmap_none"))
+	}
+
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_add_catmap"), fmt.Sprintf("add_catmap:%d", sourceID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_menu"), "category_mappings"),
+		),
+	)
+	b.sendOrEditMenu(chatID, messageID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+func (b *TelegramBot) sendCategoryTopicMenu(chatID int64, userID int64) {
+	lang := b.getLangForChat(chatID)
+	topics, err := b.storage.GetTopicsForChat(chatID)
+	if err != nil || len(topics) == 0 {
+		b.api.Send(tgbotapi.NewMessage(chatID, b.localizer.GetMessage(lang, "catmap_no_topics")))
+		b.clearUserState(userID)
+		return
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, topic := range topics {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(topic.Name, fmt.Sprintf("catmap_topic:%d", topic.ID)),
+		))
+	}
+	b.sendOrEditMenu(chatID, 0, b.localizer.GetMessage(lang, "catmap_ask_topic"), tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
+
+func (b *TelegramBot) handleCategoryMappingCallback(callback *tgbotapi.CallbackQuery, action string, data string) {
+	userID := callback.From.ID
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	lang := b.getLangForChat(chatID)
+
+	switch action {
+	case "category_mappings":
+		b.sendCategoryMappingSourcesMenu(chatID, messageID)
+	case "catmap_source":
+		sourceID, _ := strconv.ParseInt(data, 10, 64)
+		b.sendCategoryMappingsMenu(chatID, messageID, sourceID)
+	case "add_catmap":
+		sourceID, _ := strconv.ParseInt(data, 10, 64)
+		b.setUserState(userID, &ConversationState{
+			Step:          StateAwaitingCategoryPattern,
+			PendingSource: news_fetcher.Source{ID: sourceID, ChatID: chatID},
+		})
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, b.localizer.GetMessage(lang, "catmap_ask_pattern"))
+		editMsg.ParseMode = tgbotapi.ModeHTML
+		b.api.Send(editMsg)
+	case "delete_catmap":
+		parts := strings.SplitN(data, ":", 2)
+		sourceID, _ := strconv.ParseInt(parts[0], 10, 64)
+		if len(parts) == 2 {
+			mappingID, _ := strconv.ParseInt(parts[1], 10, 64)
+			if err := b.storage.DeleteCategoryMapping(mappingID, chatID); err != nil {
+				log.Printf("Failed to delete category mapping %d for chat %d: %v", mappingID, chatID, err)
+			}
+		}
+		b.sendCategoryMappingsMenu(chatID, messageID, sourceID)
+	case "catmap_topic":
+		topicID, _ := strconv.ParseInt(data, 10, 64)
+		state, ok := b.getUserState(userID)
+		if !ok || state.Step != StateAwaitingCategoryTopic {
+			return
+		}
+		matchType, pattern := parseCategoryPattern(state.PendingPattern)
+		mapping := storage.CategoryMapping{
+			ChatID:    chatID,
+			SourceID:  state.PendingSource.ID,
+			MatchType: matchType,
+			Pattern:   pattern,
+			TopicID:   topicID,
+		}
+		b.clearUserState(userID)
+		if err := b.storage.AddCategoryMapping(mapping); err != nil {
+			log.Printf("Failed to add category mapping for chat %d: %v", chatID, err)
+			b.api.Send(tgbotapi.NewEditMessageText(chatID, messageID, b.localizer.GetMessage(lang, "catmap_add_failed")))
+			return
+		}
+		b.sendCategoryMappingsMenu(chatID, messageID, state.PendingSource.ID)
+	}
+}
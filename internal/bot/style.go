@@ -0,0 +1,214 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"news-bot/config"
+	"news-bot/internal/ai"
+	"news-bot/internal/news_fetcher"
+	"news-bot/internal/telegramhtml"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// telegramCaptionLimit is the most characters a photo, audio or video
+	// caption may have.
+	telegramCaptionLimit = 1024
+	// minSummaryChars keeps a summary readable when the rest of the caption
+	// leaves little room; captions that end up too long are posted as text
+	// instead by sendArticleToChannel.
+	minSummaryChars = 100
+	maxSummaryChars = 3500
+)
+
+// summaryLengthChars are the character limits of the preset summary lengths.
+var summaryLengthChars = map[string]int{
+	config.SummaryLengthShort:  300,
+	config.SummaryLengthMedium: 600,
+	config.SummaryLengthLong:   1000,
+}
+
+var (
+	summaryLengths = []string{config.SummaryLengthFree, config.SummaryLengthShort, config.SummaryLengthMedium, config.SummaryLengthLong}
+	summaryFormats = []string{config.SummaryFormatFree, config.SummaryFormatParagraph, config.SummaryFormatBullets, config.SummaryFormatThread}
+	summaryEmojis  = []string{config.SummaryEmojiFree, config.SummaryEmojiNone, config.SummaryEmojiFew, config.SummaryEmojiMany}
+)
+
+// summaryMaxChars returns the chat's summary length limit, or 0 if the prompt
+// decides the length.
+func summaryMaxChars(chatCfg *config.Config) int {
+	if chatCfg.SummaryLength == config.SummaryLengthCustom {
+		return chatCfg.SummaryMaxChars
+	}
+	return summaryLengthChars[chatCfg.SummaryLength]
+}
+
+// summaryStyle returns the style to request for an article. When the article
+// will be posted with a photo or media caption, the length limit is lowered
+// to what fits in the caption next to the rest of the message template. The
+// fields the model fills in are not known yet, so fitSummaryToCaption makes
+// sure the summary fits once they are.
+func (b *TelegramBot) summaryStyle(chatCfg *config.Config, article *news_fetcher.Article, source news_fetcher.Source) ai.SummaryStyle {
+	style := ai.SummaryStyle{MaxChars: summaryMaxChars(chatCfg), Format: chatCfg.SummaryFormat, Emoji: chatCfg.SummaryEmoji}
+	if style.MaxChars == 0 || !postsWithCaption(article) {
+		return style
+	}
+	room := telegramCaptionLimit - telegramLength(b.formatCaption(article, &ai.Summary{}, source, chatCfg))
+	if room < style.MaxChars {
+		style.MaxChars = max(room, minSummaryChars)
+	}
+	return style
+}
+
+// fitSummaryToCaption cuts a generated summary whose caption would be over
+// the caption limit once the headline, key points and hashtags the model
+// wrote are filled in. It runs before formatSummaryHTML, so the caption is
+// measured on a formatted copy, as it will be posted.
+func (b *TelegramBot) fitSummaryToCaption(chatCfg *config.Config, article *news_fetcher.Article, summary *ai.Summary, source news_fetcher.Source) {
+	if summaryMaxChars(chatCfg) == 0 || !postsWithCaption(article) {
+		return
+	}
+	posted := *summary
+	posted.KeyPoints = append([]string(nil), summary.KeyPoints...)
+	posted.Hashtags = append([]string(nil), summary.Hashtags...)
+	formatSummaryHTML(&posted)
+	over := telegramLength(b.formatCaption(article, &posted, source, chatCfg)) - telegramCaptionLimit
+	if over > 0 {
+		summary.Text = ai.TruncateSummary(summary.Text, max(ai.TextLength(summary.Text)-over, minSummaryChars))
+	}
+}
+
+// postsWithCaption reports whether the article is posted as a photo or media
+// message, whose caption is limited to telegramCaptionLimit characters.
+func postsWithCaption(article *news_fetcher.Article) bool {
+	return article.ImageURL != "" || (article.MediaURL != "" && isAttachableMedia(article))
+}
+
+// telegramLength counts the visible characters of Telegram HTML the way
+// Telegram does, in UTF-16 code units.
+func telegramLength(text string) int {
+	return ai.TextLength(telegramhtml.StripTags(text))
+}
+
+// describeSummaryStyle returns the chat's summary style for display.
+func (b *TelegramBot) describeSummaryStyle(lang string, cfg *config.Config) string {
+	length := b.localizer.GetMessage(lang, "summary_length_"+config.SummaryLengthFree)
+	if limit := summaryMaxChars(cfg); limit > 0 {
+		length = fmt.Sprintf(b.localizer.GetMessage(lang, "summary_length_chars"), limit)
+		if cfg.SummaryLength != config.SummaryLengthCustom {
+			length = b.localizer.GetMessage(lang, "summary_length_"+cfg.SummaryLength) + " (" + length + ")"
+		}
+	}
+	return fmt.Sprintf("%s · %s · %s", length,
+		b.localizer.GetMessage(lang, "summary_format_"+styleValue(summaryFormats, cfg.SummaryFormat)),
+		b.localizer.GetMessage(lang, "summary_emoji_"+styleValue(summaryEmojis, cfg.SummaryEmoji)))
+}
+
+// styleValue returns value if it is one of values, or else the first of them.
+func styleValue(values []string, value string) string {
+	for _, candidate := range values {
+		if candidate == value {
+			return value
+		}
+	}
+	return values[0]
+}
+
+// nextStyleValue returns the value after current in values, wrapping around.
+func nextStyleValue(values []string, current string) string {
+	for i, value := range values {
+		if value == current {
+			return values[(i+1)%len(values)]
+		}
+	}
+	return values[0]
+}
+
+func (b *TelegramBot) sendSummaryStyleMenu(chatID int64, messageID int) {
+	lang := b.getLangForChat(chatID)
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for summary style menu for chat %d: %v", chatID, err)
+		return
+	}
+
+	text := fmt.Sprintf(b.localizer.GetMessage(lang, "summary_style_menu_title"), b.describeSummaryStyle(lang, cfg), telegramCaptionLimit)
+	length := b.localizer.GetMessage(lang, "summary_length_"+styleValue(summaryLengths, cfg.SummaryLength))
+	if cfg.SummaryLength == config.SummaryLengthCustom {
+		length = fmt.Sprintf(b.localizer.GetMessage(lang, "summary_length_chars"), cfg.SummaryMaxChars)
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(b.localizer.GetMessage(lang, "btn_summary_length"), length), "cycle_summary_length"),
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_summary_max_chars"), "edit_summary_max_chars"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(b.localizer.GetMessage(lang, "btn_summary_format"), b.localizer.GetMessage(lang, "summary_format_"+styleValue(summaryFormats, cfg.SummaryFormat))), "cycle_summary_format"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf(b.localizer.GetMessage(lang, "btn_summary_emoji"), b.localizer.GetMessage(lang, "summary_emoji_"+styleValue(summaryEmojis, cfg.SummaryEmoji))), "cycle_summary_emoji"),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_main_settings"), "back_to_settings"),
+		),
+	)
+	b.sendOrEditMenu(chatID, messageID, text, keyboard)
+}
+
+func (b *TelegramBot) handleSummaryStyleCallback(callback *tgbotapi.CallbackQuery, action string) {
+	userID := callback.From.ID
+	chatID := callback.Message.Chat.ID
+	messageID := callback.Message.MessageID
+	lang := b.getLangForChat(chatID)
+
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Error getting chat config for %d: %v", chatID, err)
+		return
+	}
+	column, value := "", ""
+	switch action {
+	case "summary_style_menu":
+		b.clearUserState(userID)
+	case "cycle_summary_length":
+		column, value = "summary_length", nextStyleValue(summaryLengths, cfg.SummaryLength)
+	case "cycle_summary_format":
+		column, value = "summary_format", nextStyleValue(summaryFormats, cfg.SummaryFormat)
+	case "cycle_summary_emoji":
+		column, value = "summary_emoji", nextStyleValue(summaryEmojis, cfg.SummaryEmoji)
+	case "edit_summary_max_chars":
+		b.setUserState(userID, &ConversationState{Step: StateAwaitingSummaryLength})
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, fmt.Sprintf(b.localizer.GetMessage(lang, "ask_summary_max_chars"), minSummaryChars, maxSummaryChars, telegramCaptionLimit))
+		editMsg.ParseMode = tgbotapi.ModeHTML
+		b.api.Send(editMsg)
+		return
+	}
+	if column != "" {
+		if err := b.storage.UpdateChatConfig(chatID, column, value); err != nil {
+			log.Printf("Failed to update %s for chat %d: %v", column, chatID, err)
+		}
+	}
+	b.sendSummaryStyleMenu(chatID, messageID)
+}
+
+// updateSummaryMaxChars validates and saves a custom summary length limit.
+// It returns the error message to show, if any.
+func (b *TelegramBot) updateSummaryMaxChars(chatID int64, text string) string {
+	lang := b.getLangForChat(chatID)
+	limit, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil || limit < minSummaryChars || limit > maxSummaryChars {
+		return fmt.Sprintf(b.localizer.GetMessage(lang, "invalid_summary_max_chars"), minSummaryChars, maxSummaryChars)
+	}
+	if err := b.storage.UpdateChatConfig(chatID, "summary_max_chars", limit); err != nil {
+		log.Printf("Failed to update summary_max_chars for chat %d: %v", chatID, err)
+		return b.localizer.GetMessage(lang, "settings_error")
+	}
+	if err := b.storage.UpdateChatConfig(chatID, "summary_length", config.SummaryLengthCustom); err != nil {
+		log.Printf("Failed to update summary_length for chat %d: %v", chatID, err)
+		return b.localizer.GetMessage(lang, "settings_error")
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package bot
+
+import (
+	"encoding/json"
+	"log"
+	"news-bot/internal/ai"
+	"news-bot/internal/storage"
+	"time"
+)
+
+// summaryCacheTTL is how long a cached summary is reused. Articles are rarely
+// fetched again after that, so older entries are deleted.
+const summaryCacheTTL = 7 * 24 * time.Hour
+
+// storageSummaryCache keeps summaries in the database so that chats sharing a
+// feed, model and prompt only pay for each article once.
+type storageSummaryCache struct {
+	storage *storage.Storage
+}
+
+func (c *storageSummaryCache) GetSummary(key ai.CacheKey) (*ai.Summary, bool) {
+	payload, err := c.storage.GetCachedSummary(storage.CachedSummaryKey(key), time.Now().Add(-summaryCacheTTL))
+	if err != nil {
+		if err != storage.ErrNotFound {
+			log.Printf("Failed to read summary cache for %s: %v", key.URL, err)
+		}
+		return nil, false
+	}
+	var summary ai.Summary
+	if err := json.Unmarshal([]byte(payload), &summary); err != nil {
+		log.Printf("Ignoring unreadable summary cache entry for %s: %v", key.URL, err)
+		return nil, false
+	}
+	return &summary, true
+}
+
+func (c *storageSummaryCache) PutSummary(key ai.CacheKey, summary *ai.Summary) {
+	payload, err := json.Marshal(summary)
+	if err != nil {
+		log.Printf("Failed to encode summary for the cache: %v", err)
+		return
+	}
+	if err := c.storage.SaveCachedSummary(storage.CachedSummaryKey(key), string(payload)); err != nil {
+		log.Printf("Failed to save summary cache for %s: %v", key.URL, err)
+	}
+}
+
+func (b *TelegramBot) scheduleSummaryCacheCleanup() {
+	b.scheduler.AddJob(summaryCacheCleanupJobTag, 24*time.Hour, func() {
+		if err := b.storage.DeleteCachedSummariesBefore(time.Now().Add(-summaryCacheTTL)); err != nil {
+			log.Printf("Failed to clean up summary cache: %v", err)
+		}
+	})
+}
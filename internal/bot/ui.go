@@ -3,6 +3,7 @@ package bot
 import (
 	"fmt"
 	"log"
+	"news-bot/internal/news_fetcher"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
@@ -32,7 +33,7 @@ func (b *TelegramBot) sendDeleteConfirmation(chatID int64, messageID int, source
 func (b *TelegramBot) sendSourcesMenu(chatID int64, messageID int) {
 	lang := "en"
 	text := b.localizer.GetMessage(lang, "sources_menu_title")
-	sourcesKeyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_view_sources"), "view_sources"), tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_add_source"), "add_source")), tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_delete_source"), "delete_source_menu")), tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_main_settings"), "back_to_settings")))
+	sourcesKeyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_view_sources"), "view_sources"), tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_add_source"), "add_source")), tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_delete_source"), "delete_source_menu"), tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_category_mappings"), "category_mappings")), tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_main_settings"), "back_to_settings")))
 	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
 	editMsg.ParseMode = tgbotapi.ModeHTML
 	editMsg.ReplyMarkup = &sourcesKeyboard
@@ -42,7 +43,7 @@ func (b *TelegramBot) sendSourcesMenu(chatID int64, messageID int) {
 func (b *TelegramBot) handleAddSource(chatID int64, messageID int) {
 	lang := "en"
 	text := b.localizer.GetMessage(lang, "ask_source_type")
-	typeKeyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_source_type_rss"), "chose_source_type:rss"), tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_source_type_scrape"), "chose_source_type:scrape")), tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_cancel"), "manage_sources")))
+	typeKeyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_source_type_rss"), "chose_source_type:rss"), tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_source_type_scrape"), "chose_source_type:scrape"), tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_source_type_email"), "chose_source_type:email")), tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_cancel"), "manage_sources")))
 	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
 	editMsg.ReplyMarkup = &typeKeyboard
 	b.api.Send(editMsg)
@@ -58,7 +59,7 @@ func (b *TelegramBot) handleDeleteSourceMenu(chatID int64, messageID int) {
 	text := b.localizer.GetMessage(lang, "delete_source_title")
 	var rows [][]tgbotapi.InlineKeyboardButton
 	for _, source := range sources {
-		displayURL := source.URL
+		displayURL := news_fetcher.DisplayURL(source.URL)
 		if len(displayURL) > 30 {
 			displayURL = displayURL[:27] + "..."
 		}
@@ -74,29 +75,87 @@ func (b *TelegramBot) handleDeleteSourceMenu(chatID int64, messageID int) {
 }
 
 func (b *TelegramBot) sendModelSelectionMenu(chatID int64, messageID int) {
-	lang := "en"
-	text := b.localizer.GetMessage(lang, "ask_for_new_gemini_model")
+	lang := b.getLangForChat(chatID)
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for model menu for chat %d: %v", chatID, err)
+		return
+	}
 
-	availableModels := []struct {
-		DisplayName string
-		InternalID  string
-	}{
-		{"Gemini 1.5 Flash", "gemini-1.5-flash"},
-		{"Gemini 1.0 Pro", "gemini-1.0-pro"},
+	providers := b.configuredProviders(cfg)
+	text := fmt.Sprintf(b.localizer.GetMessage(lang, "ask_for_ai_provider"), b.localizer.GetMessage(lang, "ai_provider_"+chatProvider(cfg)), cfg.AIModel)
+	if len(providers) == 0 {
+		text = b.localizer.GetMessage(lang, "no_ai_providers_configured")
 	}
+
 	var rows [][]tgbotapi.InlineKeyboardButton
-	for _, model := range availableModels {
-		button := tgbotapi.NewInlineKeyboardButtonData(model.DisplayName, "set_gemini_model:"+model.InternalID)
-		rows = append(rows, tgbotapi.NewInlineKeyboardRow(button))
+	for _, provider := range providers {
+		label := b.localizer.GetMessage(lang, "ai_provider_"+provider)
+		if provider == chatProvider(cfg) {
+			label = "✅ " + label
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(label, "select_ai_provider:"+provider)))
 	}
 
+	if len(providers) > 0 {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_model_fallbacks"), "model_fallbacks")))
+	}
 	cancelButton := tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_cancel"), "cancel_edit")
 	rows = append(rows, tgbotapi.NewInlineKeyboardRow(cancelButton))
+	b.sendOrEditMenu(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
+}
 
-	modelKeyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
-	editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
-	editMsg.ReplyMarkup = &modelKeyboard
-	b.api.Send(editMsg)
+// sendProviderModelMenu lists the models of a provider as buttons. Models
+// whose callback data would be too long for Telegram can still be typed in.
+func (b *TelegramBot) sendProviderModelMenu(chatID int64, messageID int, provider string) {
+	lang := b.getLangForChat(chatID)
+	text := fmt.Sprintf(b.localizer.GetMessage(lang, "ask_for_new_gemini_model"), b.localizer.GetMessage(lang, "ai_provider_"+provider))
+
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Failed to get chat config for model menu for chat %d: %v", chatID, err)
+		return
+	}
+	models, err := b.availableModels(chatID, cfg, provider)
+	if err != nil {
+		log.Printf("Failed to list models of %s for chat %d: %v", provider, chatID, err)
+		text += "\n\n" + b.localizer.GetMessage(lang, "model_list_failed")
+	}
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	shown := 0
+	for _, model := range models {
+		data := "set_ai_model:" + provider + ":" + model.ID
+		if len(data) > 64 {
+			continue
+		}
+		if shown == maxModelButtons {
+			text += "\n\n" + b.localizer.GetMessage(lang, "model_list_truncated")
+			break
+		}
+		label := model.DisplayName
+		if label == "" {
+			label = model.ID
+		}
+		if provider == chatProvider(cfg) && model.ID == cfg.AIModel {
+			label = "✅ " + label
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, data))
+		shown++
+		if len(row) == 2 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows,
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_enter_model_name"), "enter_ai_model:"+provider)),
+		tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_menu"), "edit_gemini_model")),
+	)
+	b.sendOrEditMenu(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(rows...))
 }
 
 func (b *TelegramBot) handleViewSources(chatID int64, messageID int) {
@@ -117,7 +176,7 @@ func (b *TelegramBot) handleViewSources(chatID int64, messageID int) {
 				topic = "N/A"
 			}
 			format := "<b>ID:</b> %d\n<b>Topic:</b> %s\n<b>Type:</b> %s\n<b>URL:</b> %s\n\n"
-			builder.WriteString(fmt.Sprintf(format, source.ID, topic, source.Type, source.URL))
+			builder.WriteString(fmt.Sprintf(format, source.ID, topic, source.Type, news_fetcher.DisplayURL(source.URL)))
 		}
 	}
 	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(b.localizer.GetMessage(lang, "btn_back_to_menu"), "manage_sources")))
@@ -249,4 +308,33 @@ func (b *TelegramBot) sendTopicSelectionMenu(chatID int64, messageID int, userID
 	}
 
 	b.api.Send(msg)
+}
+
+func (b *TelegramBot) sendOrEditMenu(chatID int64, messageID int, text string, keyboard tgbotapi.InlineKeyboardMarkup) {
+	var msg tgbotapi.Chattable
+	if messageID != 0 {
+		editMsg := tgbotapi.NewEditMessageText(chatID, messageID, text)
+		editMsg.ParseMode = tgbotapi.ModeHTML
+		editMsg.DisableWebPagePreview = true
+		editMsg.ReplyMarkup = &keyboard
+		msg = editMsg
+	} else {
+		newMsg := tgbotapi.NewMessage(chatID, text)
+		newMsg.ParseMode = tgbotapi.ModeHTML
+		newMsg.DisableWebPagePreview = true
+		newMsg.ReplyMarkup = &keyboard
+		msg = newMsg
+	}
+
+	if _, err := b.api.Send(msg); err != nil {
+		log.Printf("Failed to send menu to chat %d: %v", chatID, err)
+	}
+}
+
+func shortenForButton(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) > max {
+		return string(runes[:max-3]) + "..."
+	}
+	return text
 }
\ No newline at end of file
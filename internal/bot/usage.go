@@ -0,0 +1,164 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"news-bot/internal/ai"
+	"news-bot/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// usageContext attributes the AI calls made with ctx to a chat.
+func (b *TelegramBot) usageContext(ctx context.Context, chatID int64) context.Context {
+	return ai.WithUsageRecorder(ctx, func(usage ai.Usage) {
+		if err := b.storage.RecordAIUsage(chatID, usage.Provider, usage.Model, usage.InputTokens, usage.OutputTokens, usage.CacheHit); err != nil {
+			log.Printf("[Chat %d] Failed to record AI usage: %v", chatID, err)
+		}
+	})
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+// monthlyQuotaExceeded reports whether the chat has used up its monthly token
+// quota. A quota of zero means unlimited.
+func (b *TelegramBot) monthlyQuotaExceeded(chatID int64, quota int) (int, bool) {
+	if quota <= 0 {
+		return 0, false
+	}
+	used, err := b.storage.GetTotalTokensSince(chatID, startOfMonth(time.Now()))
+	if err != nil {
+		log.Printf("[Chat %d] Could not check monthly AI token usage: %v", chatID, err)
+		return 0, false
+	}
+	return used, used >= quota
+}
+
+// estimateCost returns the estimated price of the usage in USD, and false if
+// no price is configured for one of the models.
+func (b *TelegramBot) estimateCost(usage []storage.AIUsage) (float64, bool) {
+	total := 0.0
+	for _, u := range usage {
+		input, output, ok := b.globalCfg.ModelPrice(u.Model)
+		if !ok {
+			return 0, false
+		}
+		total += (float64(u.InputTokens)*input + float64(u.OutputTokens)*output) / 1_000_000
+	}
+	return total, true
+}
+
+func (b *TelegramBot) describeUsage(lang string, title string, usage []storage.AIUsage) string {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("<b>%s</b>\n", title))
+	if len(usage) == 0 {
+		builder.WriteString(b.localizer.GetMessage(lang, "usage_none") + "\n")
+		return builder.String()
+	}
+	totalTokens := 0
+	for _, u := range usage {
+		builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "usage_model_line"), u.Provider, u.Model, u.Requests, u.CacheHits, u.InputTokens, u.OutputTokens))
+		totalTokens += u.InputTokens + u.OutputTokens
+	}
+	cost := "N/A"
+	if estimate, ok := b.estimateCost(usage); ok {
+		cost = fmt.Sprintf("$%.4f", estimate)
+	}
+	builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "usage_total_line"), totalTokens, cost))
+	return builder.String()
+}
+
+func (b *TelegramBot) handleUsageCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := b.getLangForChat(chatID)
+
+	cfg, err := b.storage.GetChatConfig(chatID)
+	if err != nil {
+		log.Printf("Could not get config for usage of chat %d: %v", chatID, err)
+		return
+	}
+	now := time.Now()
+	daily, err := b.storage.GetAIUsageSince(chatID, startOfDay(now))
+	if err != nil {
+		log.Printf("Could not get daily AI usage for chat %d: %v", chatID, err)
+		return
+	}
+	monthly, err := b.storage.GetAIUsageSince(chatID, startOfMonth(now))
+	if err != nil {
+		log.Printf("Could not get monthly AI usage for chat %d: %v", chatID, err)
+		return
+	}
+
+	var builder strings.Builder
+	builder.WriteString(b.localizer.GetMessage(lang, "usage_title") + "\n\n")
+	builder.WriteString(b.describeUsage(lang, b.localizer.GetMessage(lang, "usage_today"), daily) + "\n")
+	builder.WriteString(b.describeUsage(lang, b.localizer.GetMessage(lang, "usage_this_month"), monthly) + "\n")
+	if cfg.MonthlyTokenQuota > 0 {
+		used := 0
+		for _, u := range monthly {
+			used += u.InputTokens + u.OutputTokens
+		}
+		builder.WriteString(fmt.Sprintf(b.localizer.GetMessage(lang, "usage_quota_line"), used, cfg.MonthlyTokenQuota))
+	} else {
+		builder.WriteString(b.localizer.GetMessage(lang, "usage_quota_unlimited"))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, builder.String())
+	msg.ParseMode = tgbotapi.ModeHTML
+	b.api.Send(msg)
+}
+
+// handleSetQuotaCommand sets the monthly token quota of the current chat, or
+// of another chat when its ID is given first.
+func (b *TelegramBot) handleSetQuotaCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := b.getLangForChat(chatID)
+	parts := strings.Fields(message.CommandArguments())
+	reply := func(text string) {
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ParseMode = tgbotapi.ModeHTML
+		b.api.Send(msg)
+	}
+
+	targetChatID := chatID
+	var quotaText string
+	switch len(parts) {
+	case 1:
+		quotaText = parts[0]
+	case 2:
+		id, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			reply(b.localizer.GetMessage(lang, "set_quota_usage"))
+			return
+		}
+		targetChatID, quotaText = id, parts[1]
+	default:
+		reply(b.localizer.GetMessage(lang, "set_quota_usage"))
+		return
+	}
+	quota, err := strconv.Atoi(quotaText)
+	if err != nil || quota < 0 {
+		reply(b.localizer.GetMessage(lang, "set_quota_usage"))
+		return
+	}
+
+	if configured, err := b.storage.IsChatConfigured(targetChatID); err != nil || !configured {
+		reply(fmt.Sprintf(b.localizer.GetMessage(lang, "set_quota_unknown_chat"), targetChatID))
+		return
+	}
+	if err := b.storage.UpdateChatConfig(targetChatID, "monthly_token_quota", quota); err != nil {
+		log.Printf("Failed to update monthly_token_quota for chat %d: %v", targetChatID, err)
+		return
+	}
+	reply(fmt.Sprintf(b.localizer.GetMessage(lang, "set_quota_success"), targetChatID, quota))
+}
@@ -0,0 +1,66 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"news-bot/config"
+	"news-bot/internal/ai"
+	"news-bot/internal/news_fetcher"
+	"strings"
+)
+
+// verifySummary checks the claims of a summary against the article text and
+// returns those without support. If the model cannot check them, their
+// numbers and names are matched instead.
+func (b *TelegramBot) verifySummary(ctx context.Context, chatCfg *config.Config, summarizer ai.Summarizer, summary *ai.Summary, article *news_fetcher.Article, chatID int64) []string {
+	source := article.TextContent
+	if source == "" {
+		source = article.Description
+	}
+	claims := ai.SummaryClaims(summary)
+	if source == "" || len(claims) == 0 {
+		return nil
+	}
+
+	if chatCfg.SummaryVerification == config.VerificationModel {
+		if verifier, ok := summarizer.(ai.Verifier); ok {
+			unsupported, err := verifier.VerifyClaims(b.usageContext(ctx, chatID), ai.VerificationRequest{Claims: claims, Source: source})
+			if err == nil {
+				return unsupported
+			}
+			log.Printf("[Chat %d] Could not verify the summary of '%s' with a model, matching its numbers and names instead: %v", chatID, article.Title, err)
+		}
+	}
+	// Names are translated along with the summary, so only numbers can be
+	// matched when the summary is in another language.
+	target := summaryLanguage(chatCfg)
+	checkNames := target == "" || target == article.Language
+	return ai.UnsupportedClaims(claims, source, checkNames)
+}
+
+// verificationNote tells the moderators which claims are unsupported.
+func (b *TelegramBot) verificationNote(chatID int64, unsupported []string) string {
+	lang := b.getLangForChat(chatID)
+	var claims strings.Builder
+	for _, claim := range unsupported {
+		claims.WriteString("\n• " + html.EscapeString(claim))
+	}
+	return fmt.Sprintf(b.localizer.GetMessage(lang, "moderation_note_unverified"), claims.String())
+}
+
+// textEscaper escapes claims the way telegramhtml escapes summary text.
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// highlightUnsupported underlines the unsupported claims where they appear
+// in the post as written.
+func highlightUnsupported(caption string, unsupported []string) string {
+	for _, claim := range unsupported {
+		escaped := textEscaper.Replace(claim)
+		if index := strings.Index(caption, escaped); index >= 0 {
+			caption = caption[:index] + "<u>" + escaped + "</u>" + caption[index+len(escaped):]
+		}
+	}
+	return caption
+}
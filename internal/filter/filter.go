@@ -0,0 +1,224 @@
+package filter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	ScopeChat   = "chat"
+	ScopeTopic  = "topic"
+	ScopeSource = "source"
+
+	ActionInclude = "include"
+	ActionExclude = "exclude"
+
+	KindKeyword = "keyword"
+	KindRegex   = "regex"
+	KindAuthor  = "author"
+	KindDomain  = "domain"
+)
+
+// Stage tells Evaluate how much of the article is known.
+type Stage int
+
+const (
+	// StageDiscovery runs before scraping, on feed metadata only.
+	StageDiscovery Stage = iota
+	// StageFullText runs after scraping, before the article is summarized.
+	StageFullText
+)
+
+// Rule is a single include or exclude filter. Keyword, author and domain rules
+// hold a comma-separated list and match if any entry matches.
+type Rule struct {
+	ID           int64
+	ChatID       int64
+	Scope        string
+	ScopeID      int64
+	Action       string
+	Kind         string
+	Pattern      string
+	BlockedCount int64
+}
+
+// Candidate is the part of an article the rules are evaluated against.
+type Candidate struct {
+	Title       string
+	Description string
+	Categories  []string
+	Author      string
+	Link        string
+	Text        string
+	TopicID     int64
+	SourceID    int64
+}
+
+var regexCache, keywordCache sync.Map
+
+// Validate checks a rule before it is saved.
+func Validate(rule Rule) error {
+	switch rule.Action {
+	case ActionInclude, ActionExclude:
+	default:
+		return fmt.Errorf("unknown action '%s'", rule.Action)
+	}
+	switch rule.Scope {
+	case ScopeChat, ScopeTopic, ScopeSource:
+	default:
+		return fmt.Errorf("unknown scope '%s'", rule.Scope)
+	}
+
+	switch rule.Kind {
+	case KindRegex:
+		if _, err := regexp.Compile("(?i)" + rule.Pattern); err != nil {
+			return fmt.Errorf("invalid regular expression: %w", err)
+		}
+	case KindKeyword, KindAuthor, KindDomain:
+		if len(splitList(rule.Pattern)) == 0 {
+			return fmt.Errorf("the list is empty")
+		}
+	default:
+		return fmt.Errorf("unknown kind '%s'", rule.Kind)
+	}
+	return nil
+}
+
+// Evaluate returns the rule that blocks the candidate, or nil if it may pass.
+//
+// An exclude rule blocks the candidate when it matches. When include rules
+// apply, the candidate must match at least one of them; the first include rule
+// is then reported as the blocker. At StageDiscovery an include rule that
+// already matches lets the candidate through, and the candidate is only held
+// back when none of the include rules needs fetched content to match.
+func Evaluate(rules []Rule, c Candidate, stage Stage) *Rule {
+	var includes []*Rule
+	for i := range rules {
+		rule := &rules[i]
+		if !appliesTo(rule, c) {
+			continue
+		}
+		if rule.Action == ActionExclude {
+			if matches(rule, c, stage) {
+				return rule
+			}
+			continue
+		}
+		includes = append(includes, rule)
+	}
+
+	if len(includes) == 0 {
+		return nil
+	}
+	for _, rule := range includes {
+		if matches(rule, c, stage) {
+			return nil
+		}
+	}
+	if stage == StageDiscovery {
+		for _, rule := range includes {
+			if needsContent(rule) {
+				return nil
+			}
+		}
+	}
+	return includes[0]
+}
+
+// needsContent reports whether a rule may still match once the article has
+// been fetched. Only domain rules are fully decided by the feed entry.
+func needsContent(rule *Rule) bool {
+	return rule.Kind != KindDomain
+}
+
+func appliesTo(rule *Rule, c Candidate) bool {
+	switch rule.Scope {
+	case ScopeChat:
+		return true
+	case ScopeTopic:
+		return rule.ScopeID == c.TopicID
+	case ScopeSource:
+		return rule.ScopeID == c.SourceID
+	}
+	return false
+}
+
+func matches(rule *Rule, c Candidate, stage Stage) bool {
+	fields := []string{c.Title, c.Description, strings.Join(c.Categories, " ")}
+	if stage == StageFullText {
+		fields = append(fields, c.Text)
+	}
+	haystack := strings.ToLower(strings.Join(fields, "\n"))
+
+	switch rule.Kind {
+	case KindKeyword:
+		for _, keyword := range splitList(rule.Pattern) {
+			if compileKeyword(keyword).MatchString(haystack) {
+				return true
+			}
+		}
+	case KindRegex:
+		if re := compile(rule.Pattern); re != nil {
+			return re.MatchString(haystack)
+		}
+	case KindAuthor:
+		author := strings.ToLower(c.Author)
+		if author == "" {
+			return false
+		}
+		for _, name := range splitList(rule.Pattern) {
+			if strings.Contains(author, name) {
+				return true
+			}
+		}
+	case KindDomain:
+		u, err := url.Parse(c.Link)
+		if err != nil {
+			return false
+		}
+		host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+		for _, domain := range splitList(rule.Pattern) {
+			domain = strings.TrimPrefix(domain, "www.")
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func compile(pattern string) *regexp.Regexp {
+	if cached, ok := regexCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil
+	}
+	regexCache.Store(pattern, re)
+	return re
+}
+
+// compileKeyword returns a pattern that matches keyword as a whole word, so
+// that "ai" does not match "said". Use a regex rule to match part of a word.
+func compileKeyword(keyword string) *regexp.Regexp {
+	if cached, ok := keywordCache.Load(keyword); ok {
+		return cached.(*regexp.Regexp)
+	}
+	re := regexp.MustCompile(`(?i)(?:^|[^\pL\pN_])` + regexp.QuoteMeta(keyword) + `(?:$|[^\pL\pN_])`)
+	keywordCache.Store(keyword, re)
+	return re
+}
+
+func splitList(pattern string) []string {
+	var items []string
+	for _, item := range strings.Split(pattern, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
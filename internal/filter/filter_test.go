@@ -0,0 +1,73 @@
+package filter
+
+import "testing"
+
+func TestMatchesKeyword(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		text    string
+		want    bool
+	}{
+		{"whole word", "ai", "New AI model released", true},
+		{"not inside a word", "ai", "He said it again in Spain", false},
+		{"any entry of the list", "election, parliament", "Parliament votes today", true},
+		{"phrase", "climate change", "Fighting climate change together", true},
+		{"punctuation around the word", "ai", "Is it (AI)?", true},
+		{"symbols in the keyword", "c++", "Why C++ still matters", true},
+		{"non-ASCII letters", "café", "Le café du coin", true},
+		{"non-ASCII word boundary", "caf", "Le café du coin", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &Rule{Kind: KindKeyword, Pattern: tt.pattern}
+			if got := matches(rule, Candidate{Title: tt.text}, StageDiscovery); got != tt.want {
+				t.Errorf("keyword %q on %q = %v, want %v", tt.pattern, tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	excludeSport := Rule{ID: 1, Scope: ScopeChat, Action: ActionExclude, Kind: KindKeyword, Pattern: "sport"}
+	includeTech := Rule{ID: 2, Scope: ScopeChat, Action: ActionInclude, Kind: KindKeyword, Pattern: "tech"}
+	includeScience := Rule{ID: 3, Scope: ScopeChat, Action: ActionInclude, Kind: KindRegex, Pattern: `scien(ce|tific)`}
+	includeDomain := Rule{ID: 4, Scope: ScopeChat, Action: ActionInclude, Kind: KindDomain, Pattern: "example.com"}
+	excludeTopic := Rule{ID: 5, Scope: ScopeTopic, ScopeID: 7, Action: ActionExclude, Kind: KindKeyword, Pattern: "rumor"}
+	excludeAuthor := Rule{ID: 6, Scope: ScopeSource, ScopeID: 9, Action: ActionExclude, Kind: KindAuthor, Pattern: "jane doe"}
+
+	tests := []struct {
+		name      string
+		rules     []Rule
+		candidate Candidate
+		stage     Stage
+		want      int64
+	}{
+		{"no rules", nil, Candidate{Title: "Anything"}, StageFullText, 0},
+		{"exclude matches", []Rule{excludeSport}, Candidate{Title: "Sport results"}, StageDiscovery, 1},
+		{"exclude does not match", []Rule{excludeSport}, Candidate{Title: "Transport news"}, StageDiscovery, 0},
+		{"exclude wins over include", []Rule{includeTech, excludeSport}, Candidate{Title: "Tech in sport"}, StageFullText, 1},
+		{"one include matches", []Rule{includeTech, includeScience}, Candidate{Title: "A scientific study"}, StageFullText, 0},
+		{"no include matches", []Rule{includeTech, includeScience}, Candidate{Title: "Local weather"}, StageFullText, 2},
+		{"include deferred until the text is known", []Rule{includeTech}, Candidate{Title: "Local weather"}, StageDiscovery, 0},
+		{"include matches in the text", []Rule{includeTech}, Candidate{Title: "Gadgets", Text: "The tech behind it"}, StageFullText, 0},
+		{"text is ignored at discovery", []Rule{excludeSport}, Candidate{Title: "News", Text: "sport"}, StageDiscovery, 0},
+		{"domain include decided at discovery", []Rule{includeDomain}, Candidate{Link: "https://other.org/a"}, StageDiscovery, 4},
+		{"domain include matches a subdomain", []Rule{includeDomain}, Candidate{Link: "https://news.example.com/a"}, StageDiscovery, 0},
+		{"topic rule applies to its topic", []Rule{excludeTopic}, Candidate{Title: "A rumor", TopicID: 7}, StageDiscovery, 5},
+		{"topic rule skips other topics", []Rule{excludeTopic}, Candidate{Title: "A rumor", TopicID: 8}, StageDiscovery, 0},
+		{"source author rule", []Rule{excludeAuthor}, Candidate{Author: "Jane Doe", SourceID: 9}, StageDiscovery, 6},
+		{"author rule without an author", []Rule{excludeAuthor}, Candidate{SourceID: 9}, StageDiscovery, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got int64
+			if rule := Evaluate(tt.rules, tt.candidate, tt.stage); rule != nil {
+				got = rule.ID
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate blocked by rule %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
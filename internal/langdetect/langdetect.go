@@ -0,0 +1,195 @@
+// Package langdetect guesses the language of article text. Languages written
+// in their own script are recognized by script; Latin-script languages are
+// told apart by counting common function words.
+package langdetect
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxWords bounds how much of an article is inspected.
+const maxWords = 1500
+
+// minHits is the number of stopword hits below which the result is unknown.
+const minHits = 3
+
+var names = map[string]string{
+	"en": "English",
+	"id": "Indonesian",
+	"de": "German",
+	"fr": "French",
+	"es": "Spanish",
+	"pt": "Portuguese",
+	"it": "Italian",
+	"nl": "Dutch",
+	"tr": "Turkish",
+	"vi": "Vietnamese",
+	"ru": "Russian",
+	"uk": "Ukrainian",
+	"ar": "Arabic",
+	"fa": "Persian",
+	"he": "Hebrew",
+	"el": "Greek",
+	"hi": "Hindi",
+	"th": "Thai",
+	"zh": "Chinese",
+	"ja": "Japanese",
+	"ko": "Korean",
+}
+
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "in", "is", "that", "for", "it", "with", "was", "on", "are", "as", "this", "by", "be", "at", "from", "have", "has", "but", "not", "they", "which", "were", "their", "will", "would", "been"},
+	"id": {"yang", "dan", "di", "ini", "itu", "dengan", "untuk", "dari", "dalam", "tidak", "akan", "pada", "juga", "ke", "karena", "ada", "oleh", "sudah", "saat", "bahwa", "tersebut", "kami", "mereka", "atau", "bisa", "lebih", "telah", "menjadi", "seperti", "hanya"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "mit", "den", "von", "sich", "auf", "ein", "eine", "für", "dem", "auch", "wird", "als", "sind", "noch", "bei", "nach", "aus", "wie", "werden", "über", "oder", "aber", "hat", "zu"},
+	"fr": {"le", "la", "les", "et", "des", "est", "une", "un", "du", "dans", "pour", "qui", "que", "pas", "sur", "au", "avec", "ce", "il", "sont", "par", "plus", "aux", "ont", "été", "mais", "cette", "ses", "leur", "nous"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "en", "un", "una", "por", "con", "para", "es", "se", "del", "al", "lo", "como", "más", "pero", "sus", "su", "fue", "este", "ha", "son", "está", "también", "entre"},
+	"pt": {"o", "os", "as", "de", "que", "e", "do", "da", "em", "um", "uma", "para", "com", "não", "no", "na", "por", "mais", "dos", "das", "foi", "ao", "ele", "seu", "sua", "ou", "ser", "quando", "também", "está"},
+	"it": {"il", "di", "che", "e", "la", "per", "un", "una", "non", "sono", "del", "della", "nel", "con", "si", "anche", "gli", "le", "da", "è", "alla", "più", "ha", "dei", "questo", "come", "ma", "essere", "stato", "delle"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "op", "te", "zijn", "voor", "met", "niet", "die", "aan", "er", "maar", "ook", "als", "bij", "om", "nog", "wordt", "door", "naar", "dan", "worden", "deze", "heeft", "werd"},
+	"tr": {"ve", "bir", "bu", "da", "de", "için", "ile", "olarak", "çok", "daha", "gibi", "olan", "ama", "en", "sonra", "kadar", "ise", "değil", "ne", "var", "olduğu", "tarafından", "yeni", "her", "şey", "göre", "ancak", "mı", "ki", "diye"},
+	"vi": {"và", "của", "là", "có", "các", "trong", "được", "cho", "không", "một", "những", "với", "người", "này", "đã", "để", "khi", "đến", "từ", "theo", "về", "cũng", "như", "tại", "ra", "sẽ", "nhiều", "năm", "vào", "đó"},
+}
+
+// stopwordIndex maps a word to the languages that list it.
+var stopwordIndex = func() map[string][]string {
+	index := make(map[string][]string)
+	for lang, words := range stopwords {
+		for _, word := range words {
+			index[word] = append(index[word], lang)
+		}
+	}
+	return index
+}()
+
+// Name returns the English name of a language code, or the code itself if it
+// is not known.
+func Name(code string) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return code
+}
+
+// IsSupported reports whether Detect can return the given code.
+func IsSupported(code string) bool {
+	_, ok := names[code]
+	return ok
+}
+
+// SupportedCodes returns the codes Detect can return, sorted.
+func SupportedCodes() []string {
+	codes := make([]string, 0, len(names))
+	for code := range names {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Detect returns the ISO 639-1 code of the text's language, or an empty
+// string if it cannot be determined with reasonable confidence.
+func Detect(text string) string {
+	if lang := detectByScript(text); lang != "" {
+		return lang
+	}
+	return detectByStopwords(text)
+}
+
+func detectByScript(text string) string {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Hiragana, r), unicode.Is(unicode.Katakana, r):
+			counts["ja"]++
+		case unicode.Is(unicode.Han, r):
+			counts["han"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["cyrillic"]++
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				counts["uk"]++
+			}
+		case unicode.Is(unicode.Arabic, r):
+			counts["arabic"]++
+			if strings.ContainsRune("پچژگ", r) {
+				counts["fa"]++
+			}
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		case unicode.Is(unicode.Devanagari, r):
+			counts["hi"]++
+		case unicode.Is(unicode.Thai, r):
+			counts["th"]++
+		}
+		if letters >= maxWords*5 {
+			break
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	dominant := func(n int) bool { return n*2 > letters }
+	switch {
+	case counts["ja"] > 0 && dominant(counts["ja"]+counts["han"]):
+		return "ja"
+	case dominant(counts["han"]):
+		return "zh"
+	case dominant(counts["cyrillic"]):
+		if counts["uk"] > 0 {
+			return "uk"
+		}
+		return "ru"
+	case dominant(counts["arabic"]):
+		if counts["fa"] > 0 {
+			return "fa"
+		}
+		return "ar"
+	}
+	for _, lang := range []string{"ko", "he", "el", "hi", "th"} {
+		if dominant(counts[lang]) {
+			return lang
+		}
+	}
+	return ""
+}
+
+func detectByStopwords(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	if len(words) > maxWords {
+		words = words[:maxWords]
+	}
+
+	scores := make(map[string]int)
+	for _, word := range words {
+		for _, lang := range stopwordIndex[word] {
+			scores[lang]++
+		}
+	}
+
+	best, bestScore, runnerUp := "", 0, 0
+	for lang, score := range scores {
+		switch {
+		case score > bestScore:
+			best, bestScore, runnerUp = lang, score, bestScore
+		case score > runnerUp:
+			runnerUp = score
+		}
+	}
+	if bestScore < minHits || bestScore == runnerUp {
+		return ""
+	}
+	return best
+}
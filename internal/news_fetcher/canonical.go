@@ -0,0 +1,51 @@
+package news_fetcher
+
+import (
+	"net/url"
+	"strings"
+)
+
+// trackingParams are query parameters that identify a campaign or referrer
+// rather than the page itself.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "mc_cid": true,
+	"mc_eid": true, "igshid": true, "ref": true, "ref_src": true, "cmpid": true,
+}
+
+// CanonicalURL normalizes an article link so that the same article reached
+// through different feeds or campaigns yields the same string. Links that
+// cannot be parsed are returned unchanged.
+func CanonicalURL(link string) string {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil || u.Host == "" {
+		return link
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme == "http" {
+		u.Scheme = "https"
+	}
+	u.Host = strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	u.Fragment = ""
+	u.User = nil
+	if u.Path != "/" {
+		u.Path = strings.TrimSuffix(u.Path, "/")
+	}
+	u.RawPath = ""
+
+	query := u.Query()
+	for name := range query {
+		if strings.HasPrefix(strings.ToLower(name), "utm_") || trackingParams[strings.ToLower(name)] {
+			query.Del(name)
+		}
+	}
+	// Encode sorts the parameters by name.
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// IsWebLink reports whether link is an http or https URL readers can open.
+// Newsletters without a web version have a link that only identifies them.
+func IsWebLink(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"news-bot/internal/secrets"
 	"strings"
 	"time"
 
@@ -18,13 +19,27 @@ type Article struct {
 	Description     string
 	TextContent     string
 	ImageURL        string
+	Author          string
 	PublicationTime *time.Time
+	MediaURL        string
+	MediaType       string
+	MediaSize       int64
+	Duration        string
+	Language        string
 }
 
 type DiscoveredArticle struct {
 	Link    string
 	Source  Source
 	PubDate *time.Time
+	// Feed metadata, used to filter articles before they are scraped.
+	Title       string
+	Description string
+	Categories  []string
+	Author      string
+	// Article is already filled in when the feed itself carries the content
+	// (e.g. podcast show notes); such links are not scraped.
+	Article *Article
 }
 
 type Source struct {
@@ -47,7 +62,9 @@ type AnalyzedLink struct {
 }
 
 type Fetcher struct {
-	parser *gofeed.Parser
+	parser      *gofeed.Parser
+	newsletters NewsletterStore
+	credentials *secrets.Box
 }
 
 func NewFetcher() *Fetcher {
@@ -67,6 +84,8 @@ func (f *Fetcher) DiscoverArticles(sources []Source, maxAgeHours int) ([]Discove
 			articlesFromSource, err = f.fetchFromRSS(source, maxAgeHours)
 		case "scrape":
 			articlesFromSource, err = f.fetchFromHomepage(source)
+		case "email":
+			articlesFromSource, err = f.fetchFromNewsletters(source, maxAgeHours)
 		default:
 			fmt.Printf("Warning: Unknown source type '%s' for URL %s\n", source.Type, source.URL)
 			continue
@@ -106,11 +125,23 @@ func (f *Fetcher) fetchFromRSS(source Source, maxAgeHours int) ([]DiscoveredArti
 			continue
 		}
 
-		discoveredArticles = append(discoveredArticles, DiscoveredArticle{
-			Link:    item.Link,
-			Source:  source,
-			PubDate: &pubDate,
-		})
+		discovered := DiscoveredArticle{
+			Link:        item.Link,
+			Source:      source,
+			PubDate:     &pubDate,
+			Title:       item.Title,
+			Description: htmlToText(item.Description),
+			Categories:  item.Categories,
+		}
+		if len(item.Authors) > 0 && item.Authors[0] != nil {
+			discovered.Author = item.Authors[0].Name
+		}
+		if enclosure, mediaType := mediaEnclosure(item); enclosure != nil {
+			discovered.Article = articleFromMediaItem(item, enclosure, mediaType)
+			discovered.Link = discovered.Article.Link
+		}
+
+		discoveredArticles = append(discoveredArticles, discovered)
 	}
 	return discoveredArticles, nil
 }
@@ -166,6 +197,7 @@ func (f *Fetcher) ScrapeArticleDetails(link string) (*Article, error) {
 		Description: article.Excerpt,
 		TextContent: article.TextContent,
 		ImageURL:    article.Image,
+		Author:      article.Byline,
 	}, nil
 }
 
@@ -0,0 +1,168 @@
+package news_fetcher
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+)
+
+const (
+	MediaTypeAudio = "audio"
+	MediaTypeVideo = "video"
+)
+
+// MaxAttachableMediaSize is the largest file Telegram will fetch from a URL
+// on behalf of a bot. Bigger enclosures are posted as links instead.
+const MaxAttachableMediaSize = 20 * 1024 * 1024
+
+var mediaExtensions = map[string]string{
+	".mp3":  MediaTypeAudio,
+	".m4a":  MediaTypeAudio,
+	".aac":  MediaTypeAudio,
+	".ogg":  MediaTypeAudio,
+	".opus": MediaTypeAudio,
+	".wav":  MediaTypeAudio,
+	".mp4":  MediaTypeVideo,
+	".m4v":  MediaTypeVideo,
+	".mov":  MediaTypeVideo,
+	".webm": MediaTypeVideo,
+}
+
+// mediaEnclosure returns the first audio or video enclosure of a feed item, if any.
+func mediaEnclosure(item *gofeed.Item) (*gofeed.Enclosure, string) {
+	for _, enclosure := range item.Enclosures {
+		if enclosure == nil || enclosure.URL == "" {
+			continue
+		}
+		mimeType := strings.ToLower(enclosure.Type)
+		switch {
+		case strings.HasPrefix(mimeType, "audio/"):
+			return enclosure, MediaTypeAudio
+		case strings.HasPrefix(mimeType, "video/"):
+			return enclosure, MediaTypeVideo
+		case mimeType == "" || mimeType == "application/octet-stream":
+			ext := strings.ToLower(path.Ext(strings.SplitN(enclosure.URL, "?", 2)[0]))
+			if mediaType, ok := mediaExtensions[ext]; ok {
+				return enclosure, mediaType
+			}
+		}
+	}
+	return nil, ""
+}
+
+// articleFromMediaItem builds an article straight from a podcast or video feed
+// item. Episode pages rarely contain readable text, so the show notes are used
+// as the article content instead of scraping the link.
+func articleFromMediaItem(item *gofeed.Item, enclosure *gofeed.Enclosure, mediaType string) *Article {
+	link := item.Link
+	if link == "" {
+		link = enclosure.URL
+	}
+
+	showNotes := item.Content
+	if strings.TrimSpace(htmlToText(showNotes)) == "" {
+		showNotes = item.Description
+	}
+	if strings.TrimSpace(htmlToText(showNotes)) == "" && item.ITunesExt != nil {
+		showNotes = item.ITunesExt.Summary
+	}
+
+	description := htmlToText(item.Description)
+	if description == "" && item.ITunesExt != nil {
+		description = item.ITunesExt.Subtitle
+	}
+
+	imageURL := ""
+	if item.Image != nil {
+		imageURL = item.Image.URL
+	}
+
+	duration := ""
+	if item.ITunesExt != nil {
+		if imageURL == "" {
+			imageURL = item.ITunesExt.Image
+		}
+		duration = formatDuration(item.ITunesExt.Duration)
+	}
+
+	author := ""
+	if len(item.Authors) > 0 && item.Authors[0] != nil {
+		author = item.Authors[0].Name
+	} else if item.ITunesExt != nil {
+		author = item.ITunesExt.Author
+	}
+
+	size, _ := strconv.ParseInt(strings.TrimSpace(enclosure.Length), 10, 64)
+
+	return &Article{
+		Title:       strings.TrimSpace(item.Title),
+		Link:        link,
+		Description: description,
+		TextContent: htmlToText(showNotes),
+		ImageURL:    imageURL,
+		Author:      author,
+		MediaURL:    enclosure.URL,
+		MediaType:   mediaType,
+		MediaSize:   size,
+		Duration:    duration,
+	}
+}
+
+// htmlToText strips markup from feed HTML while keeping paragraph breaks.
+func htmlToText(s string) string {
+	if !strings.Contains(s, "<") {
+		return strings.TrimSpace(s)
+	}
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(s))
+	if err != nil {
+		return strings.TrimSpace(s)
+	}
+	doc.Find("br").ReplaceWithHtml("\n")
+	doc.Find("p, li, h1, h2, h3, h4, div").Each(func(i int, sel *goquery.Selection) {
+		sel.AppendHtml("\n")
+	})
+
+	var lines []string
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatDuration normalizes itunes:duration values, which may be plain
+// seconds ("3725") or clock notation ("1:02:05"), into clock notation.
+func formatDuration(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+
+	var total int
+	if strings.Contains(raw, ":") {
+		for _, part := range strings.Split(raw, ":") {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return raw
+			}
+			total = total*60 + n
+		}
+	} else {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return raw
+		}
+		total = n
+	}
+
+	hours, minutes, seconds := total/3600, (total%3600)/60, total%60
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, seconds)
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/go-co-op/gocron/v2"
+	"github.com/robfig/cron/v3"
 )
 
 type Scheduler struct {
@@ -30,6 +31,23 @@ func (s *Scheduler) AddJob(tag string, interval time.Duration, job func()) {
 	}
 }
 
+// AddCronJob runs job on a crontab schedule such as "0 8 * * 1". It returns
+// an error if the crontab is invalid.
+func (s *Scheduler) AddCronJob(tag string, crontab string, job func()) error {
+	_, err := s.instance.NewJob(
+		gocron.CronJob(crontab, false),
+		gocron.NewTask(job),
+		gocron.WithTags(tag),
+	)
+	return err
+}
+
+// ValidateCron checks a standard five-field crontab.
+func ValidateCron(crontab string) error {
+	_, err := cron.ParseStandard(crontab)
+	return err
+}
+
 func (s *Scheduler) RemoveJobByTag(tag string) {
 	s.instance.RemoveByTags(tag)
 }
@@ -0,0 +1,63 @@
+// Package secrets encrypts values such as API keys before they are stored.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// Box seals and opens values with AES-256-GCM. The key is derived from a
+// secret passphrase kept outside the database.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox creates a Box whose key is the SHA-256 hash of the secret.
+func NewBox(secret string) (*Box, error) {
+	if secret == "" {
+		return nil, errors.New("secrets: empty encryption secret")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts the plaintext with a random nonce and returns the nonce and
+// ciphertext encoded as base64.
+func (b *Box) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("secrets: could not generate nonce: %w", err)
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal.
+func (b *Box) Open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("secrets: malformed value: %w", err)
+	}
+	if len(data) < b.aead.NonceSize() {
+		return "", errors.New("secrets: value too short")
+	}
+	nonce, ciphertext := data[:b.aead.NonceSize()], data[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("secrets: could not decrypt value: %w", err)
+	}
+	return string(plaintext), nil
+}
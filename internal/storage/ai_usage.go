@@ -0,0 +1,58 @@
+package storage
+
+import "time"
+
+// AIUsage sums up the AI calls a chat made with one provider and model.
+// Summaries served from the cache are counted in CacheHits, not Requests.
+type AIUsage struct {
+	Provider     string
+	Model        string
+	Requests     int
+	CacheHits    int
+	InputTokens  int
+	OutputTokens int
+}
+
+// sqliteTimeFormat matches how SQLite's CURRENT_TIMESTAMP stores timestamps.
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+func (s *Storage) RecordAIUsage(chatID int64, provider string, model string, inputTokens int, outputTokens int, cacheHit bool) error {
+	query := `INSERT INTO ai_usage (chat_id, provider, model, input_tokens, output_tokens, cache_hit) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, chatID, provider, model, inputTokens, outputTokens, cacheHit)
+	return err
+}
+
+// GetAIUsageSince returns the chat's usage per provider and model since the
+// given time.
+func (s *Storage) GetAIUsageSince(chatID int64, since time.Time) ([]AIUsage, error) {
+	query := `
+		SELECT provider, model, COUNT(*) - SUM(cache_hit), SUM(cache_hit), SUM(input_tokens), SUM(output_tokens)
+		FROM ai_usage
+		WHERE chat_id = ? AND created_at >= ?
+		GROUP BY provider, model
+		ORDER BY provider, model`
+	rows, err := s.db.Query(query, chatID, since.UTC().Format(sqliteTimeFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var usage []AIUsage
+	for rows.Next() {
+		var u AIUsage
+		if err := rows.Scan(&u.Provider, &u.Model, &u.Requests, &u.CacheHits, &u.InputTokens, &u.OutputTokens); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, nil
+}
+
+// GetTotalTokensSince returns the number of input and output tokens the chat
+// used since the given time.
+func (s *Storage) GetTotalTokensSince(chatID int64, since time.Time) (int, error) {
+	var total int
+	query := `SELECT COALESCE(SUM(input_tokens + output_tokens), 0) FROM ai_usage WHERE chat_id = ? AND created_at >= ?`
+	err := s.db.QueryRow(query, chatID, since.UTC().Format(sqliteTimeFormat)).Scan(&total)
+	return total, err
+}
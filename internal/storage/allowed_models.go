@@ -0,0 +1,48 @@
+package storage
+
+// AllowedModel is an entry of the superadmin's model allow-list. When a
+// provider has entries, chats can only choose from them. Alias is shown
+// instead of the model ID and can be typed in its place.
+type AllowedModel struct {
+	Provider string
+	Model    string
+	Alias    string
+}
+
+func (s *Storage) AddAllowedModel(model AllowedModel) error {
+	query := `INSERT OR REPLACE INTO allowed_models (provider, model, alias) VALUES (?, ?, ?)`
+	_, err := s.db.Exec(query, model.Provider, model.Model, model.Alias)
+	return err
+}
+
+// RemoveAllowedModel deletes an entry and reports whether it existed.
+func (s *Storage) RemoveAllowedModel(provider string, model string) (bool, error) {
+	query := `DELETE FROM allowed_models WHERE provider = ? AND model = ?`
+	res, err := s.db.Exec(query, provider, model)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	return affected > 0, err
+}
+
+// GetAllowedModels returns the allow-list of a provider, or of all providers
+// if provider is empty.
+func (s *Storage) GetAllowedModels(provider string) ([]AllowedModel, error) {
+	query := `SELECT provider, model, alias FROM allowed_models WHERE ? = '' OR provider = ? ORDER BY provider, model`
+	rows, err := s.db.Query(query, provider, provider)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var models []AllowedModel
+	for rows.Next() {
+		var m AllowedModel
+		if err := rows.Scan(&m.Provider, &m.Model, &m.Alias); err != nil {
+			return nil, err
+		}
+		models = append(models, m)
+	}
+	return models, nil
+}
@@ -0,0 +1,56 @@
+package storage
+
+const (
+	MatchTypeCategory = "category"
+	MatchTypePath     = "path"
+)
+
+// CategoryMapping routes feed items of one source to a topic based on the
+// item's categories or a segment of its URL path.
+type CategoryMapping struct {
+	ID                int64
+	ChatID            int64
+	SourceID          int64
+	MatchType         string
+	Pattern           string
+	TopicID           int64
+	TopicName         string
+	DestinationChatID int64
+	ReplyToMessageID  int64
+}
+
+func (s *Storage) AddCategoryMapping(mapping CategoryMapping) error {
+	query := `INSERT INTO category_mappings (chat_id, source_id, match_type, pattern, topic_id) VALUES (?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, mapping.ChatID, mapping.SourceID, mapping.MatchType, mapping.Pattern, mapping.TopicID)
+	return err
+}
+
+func (s *Storage) GetCategoryMappingsForChat(chatID int64) ([]CategoryMapping, error) {
+	query := `
+		SELECT m.id, m.source_id, m.match_type, m.pattern, m.topic_id, t.name, COALESCE(t.destination_chat_id, 0), COALESCE(t.reply_to_message_id, 0)
+		FROM category_mappings m
+		JOIN topics t ON m.topic_id = t.id
+		WHERE m.chat_id = ?
+		ORDER BY m.id`
+	rows, err := s.db.Query(query, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mappings []CategoryMapping
+	for rows.Next() {
+		mapping := CategoryMapping{ChatID: chatID}
+		if err := rows.Scan(&mapping.ID, &mapping.SourceID, &mapping.MatchType, &mapping.Pattern, &mapping.TopicID, &mapping.TopicName, &mapping.DestinationChatID, &mapping.ReplyToMessageID); err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
+
+func (s *Storage) DeleteCategoryMapping(id int64, chatID int64) error {
+	query := `DELETE FROM category_mappings WHERE id = ? AND chat_id = ?`
+	_, err := s.db.Exec(query, id, chatID)
+	return err
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DigestQueueItem is an article collected for the next digest of a chat.
+// TopicID is set when the article belongs to a topic with its own digest,
+// and zero for the chat-wide digest.
+type DigestQueueItem struct {
+	ID         int64
+	ChatID     int64
+	TopicID    int64
+	Title      string
+	Link       string
+	Summary    string
+	TopicName  string
+	SourceName string
+	CreatedAt  time.Time
+}
+
+// PendingDigest is a digest waiting for a moderator's approval.
+type PendingDigest struct {
+	ID      int64
+	ChatID  int64
+	TopicID int64
+	Text    string
+}
+
+func (s *Storage) AddDigestItem(item DigestQueueItem) error {
+	query := `INSERT INTO digest_queue (chat_id, topic_id, title, link, summary, topic_name, source_name) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, item.ChatID, item.TopicID, item.Title, item.Link, item.Summary, item.TopicName, item.SourceName)
+	return err
+}
+
+// GetDigestItems returns the chat's queued articles collected since the
+// given time, oldest first.
+func (s *Storage) GetDigestItems(chatID int64, since time.Time) ([]DigestQueueItem, error) {
+	query := `SELECT id, topic_id, title, link, summary, topic_name, source_name, created_at FROM digest_queue WHERE chat_id = ? AND created_at >= ? ORDER BY id`
+	rows, err := s.db.Query(query, chatID, since.UTC().Format(sqliteTimeFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []DigestQueueItem
+	for rows.Next() {
+		item := DigestQueueItem{ChatID: chatID}
+		if err := rows.Scan(&item.ID, &item.TopicID, &item.Title, &item.Link, &item.Summary, &item.TopicName, &item.SourceName, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (s *Storage) CountDigestItems(chatID int64) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM digest_queue WHERE chat_id = ?`, chatID).Scan(&count)
+	return count, err
+}
+
+func (s *Storage) DeleteDigestItems(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	_, err := s.db.Exec(fmt.Sprintf(`DELETE FROM digest_queue WHERE id IN (%s)`, placeholders), args...)
+	return err
+}
+
+// DeleteDigestItemsBefore drops the chat's queued articles that fell out of
+// the digest window.
+func (s *Storage) DeleteDigestItemsBefore(chatID int64, before time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM digest_queue WHERE chat_id = ? AND created_at < ?`, chatID, before.UTC().Format(sqliteTimeFormat))
+	return err
+}
+
+func (s *Storage) AddPendingDigest(digest PendingDigest) (int64, error) {
+	res, err := s.db.Exec(`INSERT INTO pending_digests (chat_id, topic_id, text) VALUES (?, ?, ?)`, digest.ChatID, digest.TopicID, digest.Text)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (s *Storage) GetPendingDigest(id int64) (*PendingDigest, error) {
+	digest := PendingDigest{ID: id}
+	err := s.db.QueryRow(`SELECT chat_id, topic_id, text FROM pending_digests WHERE id = ?`, id).Scan(&digest.ChatID, &digest.TopicID, &digest.Text)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &digest, nil
+}
+
+func (s *Storage) DeletePendingDigest(id int64) error {
+	_, err := s.db.Exec(`DELETE FROM pending_digests WHERE id = ?`, id)
+	return err
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"news-bot/internal/filter"
+)
+
+func (s *Storage) AddFilterRule(rule filter.Rule) (int64, error) {
+	query := `INSERT INTO filter_rules (chat_id, scope, scope_id, action, kind, pattern) VALUES (?, ?, ?, ?, ?, ?)`
+	res, err := s.db.Exec(query, rule.ChatID, rule.Scope, rule.ScopeID, rule.Action, rule.Kind, rule.Pattern)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (s *Storage) GetFilterRulesForChat(chatID int64) ([]filter.Rule, error) {
+	query := `SELECT id, scope, scope_id, action, kind, pattern, blocked_count FROM filter_rules WHERE chat_id = ? ORDER BY id`
+	rows, err := s.db.Query(query, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []filter.Rule
+	for rows.Next() {
+		rule := filter.Rule{ChatID: chatID}
+		if err := rows.Scan(&rule.ID, &rule.Scope, &rule.ScopeID, &rule.Action, &rule.Kind, &rule.Pattern, &rule.BlockedCount); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func (s *Storage) DeleteFilterRule(id int64, chatID int64) error {
+	query := `DELETE FROM filter_rules WHERE id = ? AND chat_id = ?`
+	_, err := s.db.Exec(query, id, chatID)
+	return err
+}
+
+func (s *Storage) IncrementFilterBlockedCount(id int64) error {
+	query := `UPDATE filter_rules SET blocked_count = blocked_count + 1 WHERE id = ?`
+	_, err := s.db.Exec(query, id)
+	return err
+}
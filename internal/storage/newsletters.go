@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"news-bot/internal/news_fetcher"
+	"strings"
+	"time"
+)
+
+// IsNewsletterRecipient reports whether an "email" source reads the
+// newsletters delivered to address by the SMTP listener.
+func (s *Storage) IsNewsletterRecipient(address string) (bool, error) {
+	rows, err := s.db.Query(`SELECT url FROM news_sources WHERE type = 'email'`)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rawURL string
+		if err := rows.Scan(&rawURL); err != nil {
+			return false, err
+		}
+		if recipient := news_fetcher.SMTPRecipient(rawURL); recipient != "" && strings.EqualFold(recipient, address) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// AddNewsletter keeps a newsletter delivered to recipient until the sources
+// reading that address have fetched it.
+func (s *Storage) AddNewsletter(recipient string, newsletter *news_fetcher.Newsletter) error {
+	query := `INSERT OR IGNORE INTO inbound_newsletters (recipient, message_id, subject, sender, html, text, received_at) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, strings.ToLower(recipient), newsletter.MessageID, newsletter.Subject, newsletter.From, newsletter.HTML, newsletter.Text, newsletter.Date.UTC().Format(sqliteTimeFormat))
+	return err
+}
+
+func (s *Storage) GetNewslettersSince(recipient string, since time.Time) ([]*news_fetcher.Newsletter, error) {
+	query := `SELECT message_id, subject, sender, html, text, received_at FROM inbound_newsletters WHERE recipient = ? AND received_at >= ? ORDER BY received_at`
+	rows, err := s.db.Query(query, strings.ToLower(recipient), since.UTC().Format(sqliteTimeFormat))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var newsletters []*news_fetcher.Newsletter
+	for rows.Next() {
+		newsletter := &news_fetcher.Newsletter{Recipients: []string{strings.ToLower(recipient)}}
+		if err := rows.Scan(&newsletter.MessageID, &newsletter.Subject, &newsletter.From, &newsletter.HTML, &newsletter.Text, &newsletter.Date); err != nil {
+			return nil, err
+		}
+		newsletters = append(newsletters, newsletter)
+	}
+	return newsletters, rows.Err()
+}
+
+// DeleteNewslettersBefore forgets newsletters received before the given time.
+func (s *Storage) DeleteNewslettersBefore(before time.Time) error {
+	_, err := s.db.Exec(`DELETE FROM inbound_newsletters WHERE received_at < ?`, before.UTC().Format(sqliteTimeFormat))
+	return err
+}
+
+// UpdateNewsSourceURL replaces the URL of a source, for example to seal the
+// credentials it carries.
+func (s *Storage) UpdateNewsSourceURL(id int64, url string) error {
+	_, err := s.db.Exec(`UPDATE news_sources SET url = ? WHERE id = ?`, url, id)
+	return err
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PostedMessage links a Telegram message the bot sent to the article it is
+// about, so that readers can ask questions about the article in reply.
+// ChatID is the chat whose settings apply, DestinationChatID the chat the
+// message was sent to.
+type PostedMessage struct {
+	ChatID            int64
+	DestinationChatID int64
+	MessageID         int
+	Link              string
+	Title             string
+	ArticleText       string
+	CreatedAt         time.Time
+}
+
+func (s *Storage) AddPostedMessage(message PostedMessage) error {
+	query := `INSERT OR REPLACE INTO posted_messages (chat_id, destination_chat_id, message_id, link, title, article_text) VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := s.db.Exec(query, message.ChatID, message.DestinationChatID, message.MessageID, message.Link, message.Title, message.ArticleText)
+	return err
+}
+
+func (s *Storage) GetPostedMessage(destinationChatID int64, messageID int) (*PostedMessage, error) {
+	query := `SELECT chat_id, link, title, article_text, created_at FROM posted_messages WHERE destination_chat_id = ? AND message_id = ?`
+	message := PostedMessage{DestinationChatID: destinationChatID, MessageID: messageID}
+	err := s.db.QueryRow(query, destinationChatID, messageID).Scan(&message.ChatID, &message.Link, &message.Title, &message.ArticleText, &message.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// DeletePostedMessagesBefore forgets messages sent before the given time,
+// after which questions about them are no longer answered.
+func (s *Storage) DeletePostedMessagesBefore(before time.Time) error {
+	query := `DELETE FROM posted_messages WHERE created_at < ?`
+	_, err := s.db.Exec(query, before.UTC().Format(sqliteTimeFormat))
+	return err
+}
@@ -0,0 +1,93 @@
+package storage
+
+import "database/sql"
+
+// PromptPreset is an AI prompt a chat saved under a name.
+type PromptPreset struct {
+	ID     int64
+	ChatID int64
+	Name   string
+	Prompt string
+}
+
+// AddPromptPreset saves a preset. Names are unique per chat.
+func (s *Storage) AddPromptPreset(chatID int64, name string, prompt string) error {
+	query := `INSERT INTO prompt_presets (chat_id, name, prompt) VALUES (?, ?, ?)`
+	_, err := s.db.Exec(query, chatID, name, prompt)
+	return err
+}
+
+func (s *Storage) GetPromptPresets(chatID int64) ([]PromptPreset, error) {
+	query := `SELECT id, name, prompt FROM prompt_presets WHERE chat_id = ? ORDER BY name`
+	rows, err := s.db.Query(query, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var presets []PromptPreset
+	for rows.Next() {
+		preset := PromptPreset{ChatID: chatID}
+		if err := rows.Scan(&preset.ID, &preset.Name, &preset.Prompt); err != nil {
+			return nil, err
+		}
+		presets = append(presets, preset)
+	}
+	return presets, nil
+}
+
+// GetPromptPreset returns one of the chat's presets.
+func (s *Storage) GetPromptPreset(id int64, chatID int64) (*PromptPreset, error) {
+	query := `SELECT name, prompt FROM prompt_presets WHERE id = ? AND chat_id = ?`
+	preset := PromptPreset{ID: id, ChatID: chatID}
+	if err := s.db.QueryRow(query, id, chatID).Scan(&preset.Name, &preset.Prompt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &preset, nil
+}
+
+func (s *Storage) DeletePromptPreset(id int64, chatID int64) error {
+	query := `DELETE FROM prompt_presets WHERE id = ? AND chat_id = ?`
+	_, err := s.db.Exec(query, id, chatID)
+	return err
+}
+
+// AdminChat is a chat a user has managed the bot in.
+type AdminChat struct {
+	ChatID int64
+	Title  string
+}
+
+// RecordAdminActivity remembers that the user managed the bot in the chat.
+func (s *Storage) RecordAdminActivity(chatID int64, userID int64, title string) error {
+	query := `INSERT INTO chat_admin_activity (chat_id, user_id, chat_title, last_seen_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(chat_id, user_id) DO UPDATE SET chat_title = excluded.chat_title, last_seen_at = excluded.last_seen_at`
+	_, err := s.db.Exec(query, chatID, userID, title)
+	return err
+}
+
+// GetAdminChats returns the configured chats the user managed the bot in,
+// most recent first.
+func (s *Storage) GetAdminChats(userID int64, limit int) ([]AdminChat, error) {
+	query := `SELECT a.chat_id, a.chat_title FROM chat_admin_activity a
+		JOIN chat_configs c ON c.chat_id = a.chat_id
+		WHERE a.user_id = ? ORDER BY a.last_seen_at DESC LIMIT ?`
+	rows, err := s.db.Query(query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var chats []AdminChat
+	for rows.Next() {
+		var chat AdminChat
+		if err := rows.Scan(&chat.ChatID, &chat.Title); err != nil {
+			return nil, err
+		}
+		chats = append(chats, chat)
+	}
+	return chats, nil
+}
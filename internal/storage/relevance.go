@@ -0,0 +1,48 @@
+package storage
+
+import "time"
+
+// maxRelevanceDrops is how many skipped articles are kept per chat.
+const maxRelevanceDrops = 50
+
+// RelevanceDrop is an article skipped because it scored below the chat's
+// relevance threshold.
+type RelevanceDrop struct {
+	Link      string
+	Title     string
+	Score     int
+	Reason    string
+	CreatedAt time.Time
+}
+
+// AddRelevanceDrop records a skipped article and forgets the chat's oldest
+// ones beyond maxRelevanceDrops.
+func (s *Storage) AddRelevanceDrop(chatID int64, drop RelevanceDrop) error {
+	query := `INSERT INTO relevance_drops (chat_id, link, title, score, reason) VALUES (?, ?, ?, ?, ?)`
+	if _, err := s.db.Exec(query, chatID, drop.Link, drop.Title, drop.Score, drop.Reason); err != nil {
+		return err
+	}
+	query = `DELETE FROM relevance_drops WHERE chat_id = ? AND id NOT IN (SELECT id FROM relevance_drops WHERE chat_id = ? ORDER BY id DESC LIMIT ?)`
+	_, err := s.db.Exec(query, chatID, chatID, maxRelevanceDrops)
+	return err
+}
+
+// GetRecentRelevanceDrops returns the chat's latest skipped articles, newest first.
+func (s *Storage) GetRecentRelevanceDrops(chatID int64, limit int) ([]RelevanceDrop, error) {
+	query := `SELECT link, title, score, reason, created_at FROM relevance_drops WHERE chat_id = ? ORDER BY id DESC LIMIT ?`
+	rows, err := s.db.Query(query, chatID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drops []RelevanceDrop
+	for rows.Next() {
+		var drop RelevanceDrop
+		if err := rows.Scan(&drop.Link, &drop.Title, &drop.Score, &drop.Reason, &drop.CreatedAt); err != nil {
+			return nil, err
+		}
+		drops = append(drops, drop)
+	}
+	return drops, nil
+}
@@ -0,0 +1,45 @@
+package storage
+
+import "database/sql"
+
+// Keys of instance-wide settings changed at runtime by the superadmin.
+const (
+	// SettingBYOKFreeChats holds how many chats may use the operator's API
+	// keys once chats are required to bring their own.
+	SettingBYOKFreeChats = "byok_free_chats"
+)
+
+// GetSetting returns the value of an instance-wide setting, or ErrNotFound.
+func (s *Storage) GetSetting(key string) (string, error) {
+	var value string
+	err := s.db.QueryRow(`SELECT value FROM settings WHERE key = ?`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (s *Storage) SetSetting(key string, value string) error {
+	_, err := s.db.Exec(`INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)`, key, value)
+	return err
+}
+
+func (s *Storage) DeleteSetting(key string) error {
+	_, err := s.db.Exec(`DELETE FROM settings WHERE key = ?`, key)
+	return err
+}
+
+// GetChatSignupRank returns the position of a chat among all configured chats
+// ordered by when they started using the bot, starting at 1.
+func (s *Storage) GetChatSignupRank(chatID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM chat_configs c, chat_configs t
+		WHERE t.chat_id = ? AND (c.created_at < t.created_at OR (c.created_at = t.created_at AND c.chat_id <= t.chat_id))`
+	var rank int
+	if err := s.db.QueryRow(query, chatID).Scan(&rank); err != nil {
+		return 0, err
+	}
+	if rank == 0 {
+		return 0, ErrNotFound
+	}
+	return rank, nil
+}
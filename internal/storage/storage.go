@@ -25,18 +25,32 @@ type Topic struct {
 	ChatID            int64
 	DestinationChatID int64
 	ReplyToMessageID  int64
+	DigestMode        bool
 }
 
 type PendingArticle struct {
-	ID         int64
-	Title      string
-	Summary    string
-	Link       string
-	ImageURL   string
-	TopicName  string
-	SourceName string
-	CreatedAt  time.Time
-	ChatID     int64
+	ID              int64
+	Title           string
+	Summary         string
+	Link            string
+	ImageURL        string
+	TopicName       string
+	SourceName      string
+	CreatedAt       time.Time
+	ChatID          int64
+	MediaURL        string
+	MediaType       string
+	MediaSize       int64
+	Duration        string
+	Language        string
+	AITitle         string
+	KeyPoints       []string
+	Hashtags        []string
+	Sentiment       string
+	Category        string
+	TitleTranslated string
+	Model           string
+	ArticleText     string
 }
 
 type ConfigWithID struct {
@@ -66,7 +80,7 @@ func (s *Storage) initSchema() error {
 		`CREATE TABLE IF NOT EXISTS chat_configs (
 			chat_id INTEGER PRIMARY KEY,
 			ai_prompt TEXT NOT NULL,
-			gemini_model TEXT NOT NULL,
+			ai_model TEXT NOT NULL,
 			message_template TEXT NOT NULL,
 			post_limit_per_run INTEGER NOT NULL,
 			enable_approval_system BOOLEAN NOT NULL,
@@ -76,7 +90,33 @@ func (s *Storage) initSchema() error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			language_code TEXT NOT NULL DEFAULT 'id',
 			schedule_interval_minutes INTEGER NOT NULL DEFAULT 60,
-			last_fetched_at DATETIME
+			last_fetched_at DATETIME,
+			ai_provider TEXT NOT NULL DEFAULT 'gemini',
+			source_language_mode TEXT NOT NULL DEFAULT 'all',
+			source_languages TEXT NOT NULL DEFAULT '',
+			summary_language TEXT NOT NULL DEFAULT '',
+			translate_title BOOLEAN NOT NULL DEFAULT FALSE,
+			monthly_token_quota INTEGER NOT NULL DEFAULT 0,
+			blocked_content_policy TEXT NOT NULL DEFAULT 'moderation',
+			model_fallbacks TEXT NOT NULL DEFAULT '',
+			byok_provider TEXT NOT NULL DEFAULT '',
+			byok_api_key TEXT NOT NULL DEFAULT '',
+			digest_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+			digest_schedule TEXT NOT NULL DEFAULT '0 8 * * *',
+			digest_window_hours INTEGER NOT NULL DEFAULT 24,
+			digest_approval BOOLEAN NOT NULL DEFAULT FALSE,
+			digest_template TEXT NOT NULL DEFAULT '',
+			article_qa_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+			topic_classification BOOLEAN NOT NULL DEFAULT FALSE,
+			classification_threshold REAL NOT NULL DEFAULT 0.6,
+			classification_fallback TEXT NOT NULL DEFAULT 'source',
+			interest_profile TEXT NOT NULL DEFAULT '',
+			relevance_threshold INTEGER NOT NULL DEFAULT 50,
+			summary_verification TEXT NOT NULL DEFAULT 'off',
+			summary_length TEXT NOT NULL DEFAULT 'free',
+			summary_max_chars INTEGER NOT NULL DEFAULT 0,
+			summary_format TEXT NOT NULL DEFAULT 'free',
+			summary_emoji TEXT NOT NULL DEFAULT 'free'
 		);`,
 
 		`CREATE TABLE IF NOT EXISTS news_sources (
@@ -96,6 +136,7 @@ func (s *Storage) initSchema() error {
 			name TEXT NOT NULL,
 			destination_chat_id INTEGER DEFAULT 0,
 			reply_to_message_id INTEGER DEFAULT 0,
+			digest_mode BOOLEAN NOT NULL DEFAULT FALSE,
 			UNIQUE(chat_id, name)
 		);`,
 
@@ -103,6 +144,8 @@ func (s *Storage) initSchema() error {
 			link TEXT NOT NULL,
 			chat_id INTEGER NOT NULL,
 			posted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			language TEXT,
+			model TEXT,
 			PRIMARY KEY (link, chat_id)
 		);`,
 
@@ -116,9 +159,155 @@ func (s *Storage) initSchema() error {
 			topic_name TEXT,
 			source_name TEXT,
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			media_url TEXT,
+			media_type TEXT,
+			media_size INTEGER DEFAULT 0,
+			media_duration TEXT,
+			language TEXT,
+			ai_title TEXT,
+			key_points TEXT,
+			hashtags TEXT,
+			sentiment TEXT,
+			category TEXT,
+			title_translated TEXT,
+			model TEXT,
 			UNIQUE(chat_id, link)
 		);`,
 
+		`CREATE TABLE IF NOT EXISTS filter_rules (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_id INTEGER NOT NULL,
+			scope TEXT NOT NULL,
+			scope_id INTEGER NOT NULL DEFAULT 0,
+			action TEXT NOT NULL,
+			kind TEXT NOT NULL,
+			pattern TEXT NOT NULL,
+			blocked_count INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS category_mappings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_id INTEGER NOT NULL,
+			source_id INTEGER NOT NULL,
+			match_type TEXT NOT NULL,
+			pattern TEXT NOT NULL,
+			topic_id INTEGER NOT NULL,
+			FOREIGN KEY(source_id) REFERENCES news_sources(id) ON DELETE CASCADE,
+			FOREIGN KEY(topic_id) REFERENCES topics(id) ON DELETE CASCADE,
+			UNIQUE(source_id, match_type, pattern)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS ai_usage (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_id INTEGER NOT NULL,
+			provider TEXT NOT NULL,
+			model TEXT NOT NULL,
+			input_tokens INTEGER NOT NULL DEFAULT 0,
+			output_tokens INTEGER NOT NULL DEFAULT 0,
+			cache_hit BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_ai_usage_chat_created ON ai_usage (chat_id, created_at);`,
+
+		`CREATE TABLE IF NOT EXISTS summary_cache (
+			url TEXT NOT NULL,
+			provider TEXT NOT NULL,
+			model TEXT NOT NULL,
+			prompt_hash TEXT NOT NULL,
+			summary TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (url, provider, model, prompt_hash)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS allowed_models (
+			provider TEXT NOT NULL,
+			model TEXT NOT NULL,
+			alias TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (provider, model)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS digest_queue (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_id INTEGER NOT NULL,
+			topic_id INTEGER NOT NULL DEFAULT 0,
+			title TEXT NOT NULL,
+			link TEXT NOT NULL,
+			summary TEXT NOT NULL,
+			topic_name TEXT NOT NULL DEFAULT '',
+			source_name TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_digest_queue_chat ON digest_queue (chat_id, created_at);`,
+
+		`CREATE TABLE IF NOT EXISTS pending_digests (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_id INTEGER NOT NULL,
+			topic_id INTEGER NOT NULL DEFAULT 0,
+			text TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS posted_messages (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_id INTEGER NOT NULL,
+			destination_chat_id INTEGER NOT NULL,
+			message_id INTEGER NOT NULL,
+			link TEXT NOT NULL,
+			title TEXT NOT NULL,
+			article_text TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(destination_chat_id, message_id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS inbound_newsletters (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			recipient TEXT NOT NULL,
+			message_id TEXT NOT NULL,
+			subject TEXT NOT NULL DEFAULT '',
+			sender TEXT NOT NULL DEFAULT '',
+			html TEXT NOT NULL DEFAULT '',
+			text TEXT NOT NULL DEFAULT '',
+			received_at DATETIME NOT NULL,
+			UNIQUE(recipient, message_id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS relevance_drops (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_id INTEGER NOT NULL,
+			link TEXT NOT NULL,
+			title TEXT NOT NULL,
+			score INTEGER NOT NULL,
+			reason TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);`,
+
+		`CREATE INDEX IF NOT EXISTS idx_relevance_drops_chat ON relevance_drops (chat_id, id);`,
+
+		`CREATE TABLE IF NOT EXISTS prompt_presets (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			chat_id INTEGER NOT NULL,
+			name TEXT NOT NULL,
+			prompt TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(chat_id, name)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS chat_admin_activity (
+			chat_id INTEGER NOT NULL,
+			user_id INTEGER NOT NULL,
+			chat_title TEXT NOT NULL DEFAULT '',
+			last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (chat_id, user_id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		);`,
+
 		`CREATE TABLE IF NOT EXISTS users (
 			user_id INTEGER PRIMARY KEY,
 			is_super_admin BOOLEAN NOT NULL DEFAULT FALSE
@@ -136,12 +325,62 @@ func (s *Storage) initSchema() error {
 		`ALTER TABLE chat_configs ADD COLUMN language_code TEXT NOT NULL DEFAULT 'id'`,
 		`ALTER TABLE chat_configs ADD COLUMN schedule_interval_minutes INTEGER NOT NULL DEFAULT 60`,
 		`ALTER TABLE chat_configs ADD COLUMN last_fetched_at DATETIME`,
+		`ALTER TABLE pending_articles ADD COLUMN media_url TEXT`,
+		`ALTER TABLE pending_articles ADD COLUMN media_type TEXT`,
+		`ALTER TABLE pending_articles ADD COLUMN media_size INTEGER DEFAULT 0`,
+		`ALTER TABLE pending_articles ADD COLUMN media_duration TEXT`,
+		`ALTER TABLE chat_configs ADD COLUMN source_language_mode TEXT NOT NULL DEFAULT 'all'`,
+		`ALTER TABLE chat_configs ADD COLUMN source_languages TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE posted_articles ADD COLUMN language TEXT`,
+		`ALTER TABLE pending_articles ADD COLUMN language TEXT`,
+		`ALTER TABLE chat_configs ADD COLUMN ai_provider TEXT NOT NULL DEFAULT 'gemini'`,
+		`ALTER TABLE chat_configs RENAME COLUMN gemini_model TO ai_model`,
+		`ALTER TABLE pending_articles ADD COLUMN ai_title TEXT`,
+		`ALTER TABLE pending_articles ADD COLUMN key_points TEXT`,
+		`ALTER TABLE pending_articles ADD COLUMN hashtags TEXT`,
+		`ALTER TABLE pending_articles ADD COLUMN sentiment TEXT`,
+		`ALTER TABLE pending_articles ADD COLUMN category TEXT`,
+		`ALTER TABLE chat_configs ADD COLUMN summary_language TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE chat_configs ADD COLUMN translate_title BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE pending_articles ADD COLUMN title_translated TEXT`,
+		`ALTER TABLE chat_configs ADD COLUMN monthly_token_quota INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE ai_usage ADD COLUMN cache_hit BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE chat_configs ADD COLUMN blocked_content_policy TEXT NOT NULL DEFAULT 'moderation'`,
+		`ALTER TABLE posted_articles ADD COLUMN model TEXT`,
+		`ALTER TABLE pending_articles ADD COLUMN model TEXT`,
+		`ALTER TABLE chat_configs ADD COLUMN model_fallbacks TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE chat_configs ADD COLUMN byok_provider TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE chat_configs ADD COLUMN byok_api_key TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE chat_configs ADD COLUMN digest_enabled BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE chat_configs ADD COLUMN digest_schedule TEXT NOT NULL DEFAULT '0 8 * * *'`,
+		`ALTER TABLE chat_configs ADD COLUMN digest_window_hours INTEGER NOT NULL DEFAULT 24`,
+		`ALTER TABLE chat_configs ADD COLUMN digest_approval BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE chat_configs ADD COLUMN digest_template TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE topics ADD COLUMN digest_mode BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE chat_configs ADD COLUMN article_qa_enabled BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE pending_articles ADD COLUMN article_text TEXT`,
+		`ALTER TABLE chat_configs ADD COLUMN topic_classification BOOLEAN NOT NULL DEFAULT FALSE`,
+		`ALTER TABLE chat_configs ADD COLUMN classification_threshold REAL NOT NULL DEFAULT 0.6`,
+		`ALTER TABLE chat_configs ADD COLUMN classification_fallback TEXT NOT NULL DEFAULT 'source'`,
+		`ALTER TABLE chat_configs ADD COLUMN interest_profile TEXT NOT NULL DEFAULT ''`,
+		`ALTER TABLE chat_configs ADD COLUMN relevance_threshold INTEGER NOT NULL DEFAULT 50`,
+		`ALTER TABLE chat_configs ADD COLUMN summary_verification TEXT NOT NULL DEFAULT 'off'`,
+		`ALTER TABLE chat_configs ADD COLUMN summary_length TEXT NOT NULL DEFAULT 'free'`,
+		`ALTER TABLE chat_configs ADD COLUMN summary_max_chars INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE chat_configs ADD COLUMN summary_format TEXT NOT NULL DEFAULT 'free'`,
+		`ALTER TABLE chat_configs ADD COLUMN summary_emoji TEXT NOT NULL DEFAULT 'free'`,
 	}
 	for _, query := range alterQueries {
 		if _, err := s.db.Exec(query); err != nil {
 		}
 	}
 
+	// Foreign keys are not enforced, so remove mappings left behind by topics
+	// deleted before DeleteTopic removed them.
+	if _, err := s.db.Exec(`DELETE FROM category_mappings WHERE topic_id NOT IN (SELECT id FROM topics)`); err != nil {
+		return fmt.Errorf("failed to remove orphaned category mappings: %w", err)
+	}
+
 	return nil
 }
 
@@ -155,38 +394,25 @@ func (s *Storage) IsChatConfigured(chatID int64) (bool, error) {
 	return exists, nil
 }
 
-func (s *Storage) CreateDefaultChatConfig(chatID int64, defaultCfg *config.Config) error {
-	query := `INSERT OR IGNORE INTO chat_configs (
-		chat_id, ai_prompt, gemini_model, message_template,
-		post_limit_per_run, enable_approval_system, approval_chat_id,
-		rss_max_age_hours, language_code, schedule_interval_minutes
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
-	_, err := s.db.Exec(query,
-		chatID,
-		defaultCfg.AiPrompt,
-		defaultCfg.GeminiModel,
-		defaultCfg.TelegramMessageTemplate,
-		defaultCfg.PostLimitPerRun,
-		defaultCfg.EnableApprovalSystem,
-		defaultCfg.ApprovalChatID,
-		defaultCfg.RSSMaxAgeHours,
-		defaultCfg.LanguageCode,
-		defaultCfg.ScheduleIntervalMinutes,
-	)
-	return err
-}
-
-func (s *Storage) GetChatConfig(chatID int64) (*config.Config, error) {
-	var cfg config.Config
-	query := `SELECT
-		ai_prompt, gemini_model, message_template,
+// chatConfigColumns lists the chat_configs columns mapped onto config.Config,
+// in the same order as the pointers returned by chatConfigFields.
+const chatConfigColumns = `ai_prompt, ai_model, message_template,
 		post_limit_per_run, enable_approval_system, approval_chat_id,
-		rss_max_age_hours, language_code, schedule_interval_minutes
-	FROM chat_configs WHERE chat_id = ?`
-
-	err := s.db.QueryRow(query, chatID).Scan(
+		rss_max_age_hours, language_code, schedule_interval_minutes,
+		source_language_mode, source_languages, ai_provider,
+		summary_language, translate_title, monthly_token_quota,
+		blocked_content_policy, model_fallbacks, byok_provider,
+		byok_api_key, digest_enabled, digest_schedule,
+		digest_window_hours, digest_approval, digest_template,
+		article_qa_enabled, topic_classification, classification_threshold,
+		classification_fallback, interest_profile, relevance_threshold,
+		summary_verification, summary_length, summary_max_chars,
+		summary_format, summary_emoji`
+
+func chatConfigFields(cfg *config.Config) []interface{} {
+	return []interface{}{
 		&cfg.AiPrompt,
-		&cfg.GeminiModel,
+		&cfg.AIModel,
 		&cfg.TelegramMessageTemplate,
 		&cfg.PostLimitPerRun,
 		&cfg.EnableApprovalSystem,
@@ -194,7 +420,66 @@ func (s *Storage) GetChatConfig(chatID int64) (*config.Config, error) {
 		&cfg.RSSMaxAgeHours,
 		&cfg.LanguageCode,
 		&cfg.ScheduleIntervalMinutes,
-	)
+		&cfg.SourceLanguageMode,
+		&cfg.SourceLanguages,
+		&cfg.AIProvider,
+		&cfg.SummaryLanguage,
+		&cfg.TranslateTitle,
+		&cfg.MonthlyTokenQuota,
+		&cfg.BlockedContentPolicy,
+		&cfg.ModelFallbacks,
+		&cfg.BYOKProvider,
+		&cfg.BYOKAPIKey,
+		&cfg.DigestEnabled,
+		&cfg.DigestSchedule,
+		&cfg.DigestWindowHours,
+		&cfg.DigestApproval,
+		&cfg.DigestTemplate,
+		&cfg.ArticleQAEnabled,
+		&cfg.TopicClassification,
+		&cfg.ClassificationThreshold,
+		&cfg.ClassificationFallback,
+		&cfg.InterestProfile,
+		&cfg.RelevanceThreshold,
+		&cfg.SummaryVerification,
+		&cfg.SummaryLength,
+		&cfg.SummaryMaxChars,
+		&cfg.SummaryFormat,
+		&cfg.SummaryEmoji,
+	}
+}
+
+func (s *Storage) CreateDefaultChatConfig(chatID int64, defaultCfg *config.Config) error {
+	fields := chatConfigFields(defaultCfg)
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(fields)+1), ", ")
+	query := fmt.Sprintf(`INSERT OR IGNORE INTO chat_configs (chat_id, %s) VALUES (%s)`, chatConfigColumns, placeholders)
+
+	values := []interface{}{chatID}
+	for _, field := range fields {
+		switch value := field.(type) {
+		case *string:
+			values = append(values, *value)
+		case *int:
+			values = append(values, *value)
+		case *int64:
+			values = append(values, *value)
+		case *bool:
+			values = append(values, *value)
+		case *float64:
+			values = append(values, *value)
+		default:
+			return fmt.Errorf("unsupported chat config field type %T", field)
+		}
+	}
+	_, err := s.db.Exec(query, values...)
+	return err
+}
+
+func (s *Storage) GetChatConfig(chatID int64) (*config.Config, error) {
+	var cfg config.Config
+	query := fmt.Sprintf(`SELECT %s FROM chat_configs WHERE chat_id = ?`, chatConfigColumns)
+
+	err := s.db.QueryRow(query, chatID).Scan(chatConfigFields(&cfg)...)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
@@ -205,12 +490,7 @@ func (s *Storage) GetChatConfig(chatID int64) (*config.Config, error) {
 }
 
 func (s *Storage) GetAllChatConfigs() ([]*ConfigWithID, error) {
-	query := `SELECT
-		chat_id, ai_prompt, gemini_model, message_template,
-		post_limit_per_run, enable_approval_system, approval_chat_id,
-		rss_max_age_hours, language_code, schedule_interval_minutes,
-		last_fetched_at
-	FROM chat_configs WHERE is_active = TRUE`
+	query := fmt.Sprintf(`SELECT chat_id, %s, last_fetched_at FROM chat_configs WHERE is_active = TRUE`, chatConfigColumns)
 
 	rows, err := s.db.Query(query)
 	if err != nil {
@@ -224,20 +504,9 @@ func (s *Storage) GetAllChatConfigs() ([]*ConfigWithID, error) {
 		var cfg config.Config
 		var lastFetched sql.NullTime
 
-		err := rows.Scan(
-			&chatID,
-			&cfg.AiPrompt,
-			&cfg.GeminiModel,
-			&cfg.TelegramMessageTemplate,
-			&cfg.PostLimitPerRun,
-			&cfg.EnableApprovalSystem,
-			&cfg.ApprovalChatID,
-			&cfg.RSSMaxAgeHours,
-			&cfg.LanguageCode,
-			&cfg.ScheduleIntervalMinutes,
-			&lastFetched,
-		)
-		if err != nil {
+		dest := append([]interface{}{&chatID}, chatConfigFields(&cfg)...)
+		dest = append(dest, &lastFetched)
+		if err := rows.Scan(dest...); err != nil {
 			return nil, err
 		}
 
@@ -257,6 +526,14 @@ func (s *Storage) UpdateChatConfig(chatID int64, key string, value interface{})
 	return err
 }
 
+// SetChatAPIKey stores a chat's own API key together with its provider, so
+// neither is ever saved without the other. Empty values remove the key.
+func (s *Storage) SetChatAPIKey(chatID int64, provider string, sealedKey string) error {
+	query := `UPDATE chat_configs SET byok_provider = ?, byok_api_key = ? WHERE chat_id = ?`
+	_, err := s.db.Exec(query, provider, sealedKey, chatID)
+	return err
+}
+
 func (s *Storage) UpdateLastFetchedTime(chatID int64, fetchTime time.Time) error {
 	query := `UPDATE chat_configs SET last_fetched_at = ? WHERE chat_id = ?`
 	_, err := s.db.Exec(query, fetchTime, chatID)
@@ -288,6 +565,15 @@ func (s *Storage) MarkAsPosted(link string, chatID int64) error {
 	return err
 }
 
+// RecordPostedArticle marks an article as handled, along with its language
+// and the model that summarized it, if any.
+func (s *Storage) RecordPostedArticle(link string, chatID int64, language string, model string) error {
+	query := `INSERT INTO posted_articles (link, chat_id, language, model) VALUES (?, ?, ?, ?)
+		ON CONFLICT(link, chat_id) DO UPDATE SET language = excluded.language, model = excluded.model`
+	_, err := s.db.Exec(query, link, chatID, language, model)
+	return err
+}
+
 func (s *Storage) IsAlreadyPosted(link string, chatID int64) (bool, error) {
 	var exists bool
 	query := `SELECT EXISTS(SELECT 1 FROM posted_articles WHERE link = ? AND chat_id = ?)`
@@ -388,7 +674,10 @@ func (s *Storage) GetAllNewsSources() ([]news_fetcher.Source, error) {
 
 func (s *Storage) DeleteNewsSource(id int64, chatID int64) error {
 	query := `DELETE FROM news_sources WHERE id = ? AND chat_id = ?`
-	_, err := s.db.Exec(query, id, chatID)
+	if _, err := s.db.Exec(query, id, chatID); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM category_mappings WHERE source_id = ? AND chat_id = ?`, id, chatID)
 	return err
 }
 
@@ -399,7 +688,7 @@ func (s *Storage) AddTopic(chatID int64, name string) error {
 }
 
 func (s *Storage) GetTopicsForChat(chatID int64) ([]Topic, error) {
-	query := `SELECT id, name, destination_chat_id, reply_to_message_id FROM topics WHERE chat_id = ? ORDER BY name`
+	query := `SELECT id, name, destination_chat_id, reply_to_message_id, digest_mode FROM topics WHERE chat_id = ? ORDER BY name`
 	rows, err := s.db.Query(query, chatID)
 	if err != nil {
 		return nil, err
@@ -410,7 +699,7 @@ func (s *Storage) GetTopicsForChat(chatID int64) ([]Topic, error) {
 	for rows.Next() {
 		var topic Topic
 		var destChatID, replyToMsgID sql.NullInt64
-		if err := rows.Scan(&topic.ID, &topic.Name, &destChatID, &replyToMsgID); err != nil {
+		if err := rows.Scan(&topic.ID, &topic.Name, &destChatID, &replyToMsgID, &topic.DigestMode); err != nil {
 			return nil, err
 		}
 		topic.ChatID = chatID
@@ -423,7 +712,10 @@ func (s *Storage) GetTopicsForChat(chatID int64) ([]Topic, error) {
 
 func (s *Storage) DeleteTopic(topicID int64, chatID int64) error {
 	query := `DELETE FROM topics WHERE id = ? AND chat_id = ?`
-	_, err := s.db.Exec(query, topicID, chatID)
+	if _, err := s.db.Exec(query, topicID, chatID); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM category_mappings WHERE topic_id = ? AND chat_id = ?`, topicID, chatID)
 	return err
 }
 
@@ -443,6 +735,12 @@ func (s *Storage) UpdateTopicDestination(topicID int64, chatID int64, destChatID
 	return err
 }
 
+func (s *Storage) SetTopicDigestMode(topicID int64, chatID int64, enabled bool) error {
+	query := `UPDATE topics SET digest_mode = ? WHERE id = ? AND chat_id = ?`
+	_, err := s.db.Exec(query, enabled, topicID, chatID)
+	return err
+}
+
 func (s *Storage) GetTopicByName(chatID int64, name string) (*Topic, error) {
 	query := `SELECT id, name, destination_chat_id, reply_to_message_id FROM topics WHERE chat_id = ? AND name = ?`
 	row := s.db.QueryRow(query, chatID, name)
@@ -462,8 +760,9 @@ func (s *Storage) GetTopicByName(chatID int64, name string) (*Topic, error) {
 }
 
 func (s *Storage) AddPendingArticle(chatID int64, article PendingArticle) (int64, error) {
-	query := `INSERT INTO pending_articles (chat_id, title, summary, link, image_url, topic_name, source_name) VALUES (?, ?, ?, ?, ?, ?, ?)`
-	res, err := s.db.Exec(query, chatID, article.Title, article.Summary, article.Link, article.ImageURL, article.TopicName, article.SourceName)
+	query := `INSERT INTO pending_articles (chat_id, title, summary, link, image_url, topic_name, source_name, media_url, media_type, media_size, media_duration, language, ai_title, key_points, hashtags, sentiment, category, title_translated, model, article_text) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := s.db.Exec(query, chatID, article.Title, article.Summary, article.Link, article.ImageURL, article.TopicName, article.SourceName, article.MediaURL, article.MediaType, article.MediaSize, article.Duration, article.Language,
+		article.AITitle, strings.Join(article.KeyPoints, "\n"), strings.Join(article.Hashtags, " "), article.Sentiment, article.Category, article.TitleTranslated, article.Model, article.ArticleText)
 	if err != nil {
 		return 0, err
 	}
@@ -471,12 +770,14 @@ func (s *Storage) AddPendingArticle(chatID int64, article PendingArticle) (int64
 }
 
 func (s *Storage) GetPendingArticle(id int64) (*PendingArticle, error) {
-	query := `SELECT id, chat_id, title, summary, link, image_url, topic_name, source_name, created_at FROM pending_articles WHERE id = ?`
+	query := `SELECT id, chat_id, title, summary, link, image_url, topic_name, source_name, created_at, media_url, media_type, media_size, media_duration, language, ai_title, key_points, hashtags, sentiment, category, title_translated, model, article_text FROM pending_articles WHERE id = ?`
 	row := s.db.QueryRow(query, id)
 
 	var article PendingArticle
-	var imageURL, topicName, sourceName sql.NullString
-	if err := row.Scan(&article.ID, &article.ChatID, &article.Title, &article.Summary, &article.Link, &imageURL, &topicName, &sourceName, &article.CreatedAt); err != nil {
+	var imageURL, topicName, sourceName, mediaURL, mediaType, duration, language sql.NullString
+	var aiTitle, keyPoints, hashtags, sentiment, category, titleTranslated, model, articleText sql.NullString
+	var mediaSize sql.NullInt64
+	if err := row.Scan(&article.ID, &article.ChatID, &article.Title, &article.Summary, &article.Link, &imageURL, &topicName, &sourceName, &article.CreatedAt, &mediaURL, &mediaType, &mediaSize, &duration, &language, &aiTitle, &keyPoints, &hashtags, &sentiment, &category, &titleTranslated, &model, &articleText); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
@@ -485,6 +786,21 @@ func (s *Storage) GetPendingArticle(id int64) (*PendingArticle, error) {
 	article.ImageURL = imageURL.String
 	article.TopicName = topicName.String
 	article.SourceName = sourceName.String
+	article.MediaURL = mediaURL.String
+	article.MediaType = mediaType.String
+	article.MediaSize = mediaSize.Int64
+	article.Duration = duration.String
+	article.Language = language.String
+	article.AITitle = aiTitle.String
+	if keyPoints.String != "" {
+		article.KeyPoints = strings.Split(keyPoints.String, "\n")
+	}
+	article.Hashtags = strings.Fields(hashtags.String)
+	article.Sentiment = sentiment.String
+	article.Category = category.String
+	article.Model = model.String
+	article.TitleTranslated = titleTranslated.String
+	article.ArticleText = articleText.String
 	return &article, nil
 }
 
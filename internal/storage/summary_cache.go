@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"database/sql"
+	"time"
+)
+
+// CachedSummaryKey identifies a summary in the summary cache.
+type CachedSummaryKey struct {
+	URL        string
+	Provider   string
+	Model      string
+	PromptHash string
+}
+
+// GetCachedSummary returns the stored summary for key if it was saved after
+// notBefore, or ErrNotFound.
+func (s *Storage) GetCachedSummary(key CachedSummaryKey, notBefore time.Time) (string, error) {
+	var summary string
+	query := `SELECT summary FROM summary_cache WHERE url = ? AND provider = ? AND model = ? AND prompt_hash = ? AND created_at >= ?`
+	err := s.db.QueryRow(query, key.URL, key.Provider, key.Model, key.PromptHash, notBefore.UTC().Format(sqliteTimeFormat)).Scan(&summary)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", ErrNotFound
+		}
+		return "", err
+	}
+	return summary, nil
+}
+
+func (s *Storage) SaveCachedSummary(key CachedSummaryKey, summary string) error {
+	query := `INSERT OR REPLACE INTO summary_cache (url, provider, model, prompt_hash, summary, created_at) VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)`
+	_, err := s.db.Exec(query, key.URL, key.Provider, key.Model, key.PromptHash, summary)
+	return err
+}
+
+// DeleteCachedSummariesBefore removes cache entries saved before the given time.
+func (s *Storage) DeleteCachedSummariesBefore(before time.Time) error {
+	query := `DELETE FROM summary_cache WHERE created_at < ?`
+	_, err := s.db.Exec(query, before.UTC().Format(sqliteTimeFormat))
+	return err
+}
@@ -0,0 +1,419 @@
+// Package telegramhtml turns model output into the subset of HTML accepted by
+// Telegram's HTML parse mode. Markdown is converted to tags, tags Telegram
+// supports are kept, everything else is escaped and tags are balanced so
+// that a message never fails with "can't parse entities".
+package telegramhtml
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// allowedTags are the tags Telegram understands in HTML mode.
+var allowedTags = map[string]bool{
+	"b": true, "strong": true, "i": true, "em": true, "u": true, "ins": true,
+	"s": true, "strike": true, "del": true, "a": true, "code": true, "pre": true,
+	"blockquote": true, "tg-spoiler": true, "span": true,
+}
+
+var (
+	tagPattern      = regexp.MustCompile(`(?i)<(/?)([a-z][a-z0-9-]*)((?:\s+(?:[a-z-]+\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'<>]+)|expandable))*)\s*(/?)>`)
+	entityPattern   = regexp.MustCompile(`^&(lt|gt|amp|quot|#[0-9]+|#x[0-9a-fA-F]+);`)
+	attrPattern     = regexp.MustCompile(`(?i)([a-z-]+)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+	codeClass       = regexp.MustCompile(`^language-[\w+#-]+$`)
+	placeholderExpr = regexp.MustCompile("\x00([0-9]+)\x00")
+
+	fencePattern   = regexp.MustCompile("^\\s*```")
+	quotePattern   = regexp.MustCompile(`^\s*&gt;\s?(.*)$`)
+	rulePattern    = regexp.MustCompile(`^\s*([-*_])(\s*[-*_]){2,}\s*$`)
+	headingPattern = regexp.MustCompile(`^\s{0,3}#{1,6}\s+(.+?)\s*#*\s*$`)
+	bulletPattern  = regexp.MustCompile(`^(\s*)[*+-]\s+(.*)$`)
+
+	codeSpanPattern    = regexp.MustCompile("`([^`]+)`")
+	linkPattern        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	boldStarPattern    = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	boldUnderPattern   = regexp.MustCompile(`__(.+?)__`)
+	strikePattern      = regexp.MustCompile(`~~(.+?)~~`)
+	italicStarPattern  = regexp.MustCompile(`(^|[^\w*])\*([^\s*](?:[^*]*[^\s*])?)\*($|[^\w*])`)
+	italicUnderPattern = regexp.MustCompile(`(^|[^\w])_([^\s_](?:[^_]*[^\s_])?)_($|[^\w])`)
+	inlinePlaceholder  = regexp.MustCompile("\x01([0-9]+)\x01")
+)
+
+// FromMarkdown converts Markdown written by a model, possibly mixed with
+// HTML, into Telegram HTML.
+func FromMarkdown(text string) string {
+	text = strings.NewReplacer("\x00", "", "\x01", "", "\r\n", "\n").Replace(text)
+	protected, tags := protectTags(text)
+	converted := convertBlocks(protected)
+	restored := placeholderExpr.ReplaceAllStringFunc(converted, func(m string) string {
+		index, _ := strconv.Atoi(strings.Trim(m, "\x00"))
+		return tags[index]
+	})
+	return balance(restored)
+}
+
+// protectTags escapes the text and replaces supported tags with placeholders
+// so that the Markdown conversion leaves them alone.
+func protectTags(text string) (string, []string) {
+	var builder strings.Builder
+	var tags []string
+	last := 0
+	for _, m := range tagPattern.FindAllStringSubmatchIndex(text, -1) {
+		name := strings.ToLower(text[m[4]:m[5]])
+		attrs := ""
+		if m[6] >= 0 {
+			attrs = text[m[6]:m[7]]
+		}
+		var tag string
+		switch {
+		case name == "br":
+			builder.WriteString(escapeText(text[last:m[0]]))
+			builder.WriteString("\n")
+			last = m[1]
+			continue
+		case allowedTags[name] && m[8] == m[9]:
+			tag = cleanTag(m[3] > m[2], name, attrs)
+		}
+		if tag == "" {
+			continue
+		}
+		builder.WriteString(escapeText(text[last:m[0]]))
+		builder.WriteString("\x00" + strconv.Itoa(len(tags)) + "\x00")
+		tags = append(tags, tag)
+		last = m[1]
+	}
+	builder.WriteString(escapeText(text[last:]))
+	return builder.String(), tags
+}
+
+// cleanTag rebuilds a supported tag with only the attributes Telegram
+// accepts. It returns an empty string for tags that cannot be kept.
+func cleanTag(closing bool, name string, attrs string) string {
+	if closing {
+		return "</" + name + ">"
+	}
+	values := make(map[string]string)
+	for _, attr := range attrPattern.FindAllStringSubmatch(attrs, -1) {
+		values[strings.ToLower(attr[1])] = strings.Trim(attr[2], `"'`)
+	}
+	switch name {
+	case "a":
+		href := values["href"]
+		if !isSafeURL(href) {
+			return ""
+		}
+		return `<a href="` + escapeAttr(href) + `">`
+	case "span":
+		if !strings.Contains(values["class"], "tg-spoiler") {
+			return ""
+		}
+		return `<span class="tg-spoiler">`
+	case "code":
+		if codeClass.MatchString(values["class"]) {
+			return `<code class="` + values["class"] + `">`
+		}
+	case "blockquote":
+		if strings.Contains(strings.ToLower(attrs), "expandable") {
+			return "<blockquote expandable>"
+		}
+	}
+	return "<" + name + ">"
+}
+
+func isSafeURL(url string) bool {
+	lower := strings.ToLower(strings.TrimSpace(url))
+	for _, scheme := range []string{"http://", "https://", "tg://", "mailto:"} {
+		if strings.HasPrefix(lower, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// escapeText escapes <, > and & while keeping the entities Telegram knows.
+func escapeText(text string) string {
+	var builder strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '<':
+			builder.WriteString("&lt;")
+		case '>':
+			builder.WriteString("&gt;")
+		case '&':
+			if entityPattern.MatchString(text[i:]) {
+				builder.WriteByte(c)
+			} else {
+				builder.WriteString("&amp;")
+			}
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+func escapeAttr(value string) string {
+	return strings.ReplaceAll(escapeText(value), `"`, "&quot;")
+}
+
+// convertBlocks handles the line-based Markdown constructs: code fences,
+// quotes, rules, headings and bullets.
+func convertBlocks(text string) string {
+	var out, fence, quote []string
+	inFence := false
+	flushQuote := func() {
+		if len(quote) > 0 {
+			out = append(out, "<blockquote>"+strings.Join(quote, "\n")+"</blockquote>")
+			quote = nil
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if fencePattern.MatchString(line) {
+			if inFence {
+				out = append(out, "<pre>"+strings.Join(fence, "\n")+"</pre>")
+				fence = nil
+			} else {
+				flushQuote()
+			}
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			fence = append(fence, line)
+			continue
+		}
+		if m := quotePattern.FindStringSubmatch(line); m != nil {
+			quote = append(quote, convertLine(m[1]))
+			continue
+		}
+		flushQuote()
+		out = append(out, convertLine(line))
+	}
+	flushQuote()
+	if inFence {
+		out = append(out, "<pre>"+strings.Join(fence, "\n")+"</pre>")
+	}
+	return strings.Join(out, "\n")
+}
+
+func convertLine(line string) string {
+	if rulePattern.MatchString(line) {
+		return ""
+	}
+	if m := headingPattern.FindStringSubmatch(line); m != nil {
+		return "<b>" + convertInline(m[1]) + "</b>"
+	}
+	if m := bulletPattern.FindStringSubmatch(line); m != nil {
+		return m[1] + "• " + convertInline(m[2])
+	}
+	return convertInline(line)
+}
+
+// convertInline handles code spans, links and emphasis within a line.
+func convertInline(text string) string {
+	var spans []string
+	hold := func(html string) string {
+		spans = append(spans, html)
+		return "\x01" + strconv.Itoa(len(spans)-1) + "\x01"
+	}
+	text = codeSpanPattern.ReplaceAllStringFunc(text, func(m string) string {
+		return hold("<code>" + m[1:len(m)-1] + "</code>")
+	})
+	text = linkPattern.ReplaceAllStringFunc(text, func(m string) string {
+		parts := linkPattern.FindStringSubmatch(m)
+		if !isSafeURL(parts[2]) {
+			return parts[1]
+		}
+		return hold(`<a href="`+strings.ReplaceAll(parts[2], `"`, "&quot;")+`">`) + parts[1] + hold("</a>")
+	})
+
+	text = boldStarPattern.ReplaceAllString(text, "<b>$1</b>")
+	text = boldUnderPattern.ReplaceAllString(text, "<b>$1</b>")
+	text = strikePattern.ReplaceAllString(text, "<s>$1</s>")
+	// Neighbouring matches share the character between them, so repeat until
+	// every one has been replaced.
+	for _, pattern := range []*regexp.Regexp{italicStarPattern, italicUnderPattern} {
+		for {
+			replaced := pattern.ReplaceAllString(text, "$1<i>$2</i>$3")
+			if replaced == text {
+				break
+			}
+			text = replaced
+		}
+	}
+
+	return inlinePlaceholder.ReplaceAllStringFunc(text, func(m string) string {
+		index, _ := strconv.Atoi(strings.Trim(m, "\x01"))
+		return spans[index]
+	})
+}
+
+// balance drops closing tags without an opening tag and closes tags left
+// open. Tags inside code are shown as text since Telegram doesn't allow them
+// there.
+func balance(text string) string {
+	var builder strings.Builder
+	var stack []string
+	last := 0
+	for _, m := range tagPattern.FindAllStringSubmatchIndex(text, -1) {
+		builder.WriteString(text[last:m[0]])
+		last = m[1]
+		tag := text[m[0]:m[1]]
+		name := strings.ToLower(text[m[4]:m[5]])
+		inCode := contains(stack, "code") || contains(stack, "pre")
+
+		if m[3] > m[2] {
+			index := -1
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] == name {
+					index = i
+					break
+				}
+			}
+			if index < 0 || inCode && name != "code" && name != "pre" {
+				if inCode {
+					builder.WriteString(escapeText(tag))
+				}
+				continue
+			}
+			for i := len(stack) - 1; i >= index; i-- {
+				builder.WriteString("</" + stack[i] + ">")
+			}
+			stack = stack[:index]
+			continue
+		}
+
+		if inCode && !(name == "code" && stack[len(stack)-1] == "pre") {
+			builder.WriteString(escapeText(tag))
+			continue
+		}
+		if name == "a" && contains(stack, "a") {
+			continue
+		}
+		stack = append(stack, name)
+		builder.WriteString(tag)
+	}
+	builder.WriteString(text[last:])
+	for i := len(stack) - 1; i >= 0; i-- {
+		builder.WriteString("</" + stack[i] + ">")
+	}
+	return builder.String()
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// StripTags returns the plain text of Telegram HTML.
+func StripTags(text string) string {
+	return html.UnescapeString(tagPattern.ReplaceAllString(text, ""))
+}
+
+// Split cuts Telegram HTML into parts of at most limit visible characters,
+// preferring line breaks. Long lines are never cut inside a tag or an
+// entity, and tags open at a cut are closed at the end of the part and
+// reopened at the start of the next one, so that every part can be sent on
+// its own.
+func Split(text string, limit int) []string {
+	var chunks []string
+	var current strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		for visibleLength(line) > limit {
+			if current.Len() > 0 {
+				chunks = append(chunks, current.String())
+				current.Reset()
+			}
+			cut := safeCut(line, limit)
+			chunks = append(chunks, line[:cut])
+			line = line[cut:]
+		}
+		if current.Len() > 0 && visibleLength(current.String())+1+visibleLength(line) > limit {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n")
+		}
+		current.WriteString(line)
+	}
+	chunks = append(chunks, current.String())
+
+	var parts []string
+	var open []string
+	for _, chunk := range chunks {
+		reopen := strings.Join(open, "")
+		open = openTags(open, chunk)
+		if strings.TrimSpace(StripTags(chunk)) == "" {
+			continue
+		}
+		parts = append(parts, balance(reopen+strings.TrimSpace(chunk)))
+	}
+	return parts
+}
+
+// visibleLength returns the number of characters Telegram shows for text.
+func visibleLength(text string) int {
+	return utf8.RuneCountInString(StripTags(text))
+}
+
+// safeCut returns the byte offset after the first limit visible characters
+// of line. Tags take no room and entities count as one character, so the
+// offset never falls inside either of them.
+func safeCut(line string, limit int) int {
+	tags := tagPattern.FindAllStringIndex(line, -1)
+	count := 0
+	for i := 0; i < len(line); {
+		if count == limit {
+			return i
+		}
+		if len(tags) > 0 && tags[0][0] == i {
+			i = tags[0][1]
+			tags = tags[1:]
+			continue
+		}
+		count++
+		if entity := entityPattern.FindString(line[i:]); entity != "" {
+			i += len(entity)
+		} else {
+			_, size := utf8.DecodeRuneInString(line[i:])
+			i += size
+		}
+	}
+	return len(line)
+}
+
+// openTags returns the opening tags still open after text, given the tags
+// that were open before it.
+func openTags(open []string, text string) []string {
+	open = append([]string(nil), open...)
+	for _, m := range tagPattern.FindAllStringSubmatchIndex(text, -1) {
+		name := strings.ToLower(text[m[4]:m[5]])
+		if m[3] == m[2] {
+			open = append(open, text[m[0]:m[1]])
+			continue
+		}
+		for i := len(open) - 1; i >= 0; i-- {
+			if tagName(open[i]) == name {
+				open = append(open[:i], open[i+1:]...)
+				break
+			}
+		}
+	}
+	return open
+}
+
+func tagName(tag string) string {
+	m := tagPattern.FindStringSubmatch(tag)
+	if m == nil {
+		return ""
+	}
+	return strings.ToLower(m[2])
+}
@@ -0,0 +1,74 @@
+package telegramhtml
+
+import "testing"
+
+func TestFromMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"nested emphasis", "**bold *it* bold**", "<b>bold <i>it</i> bold</b>"},
+		{"unclosed markdown emphasis", "**open", "**open"},
+		{"neighbouring italics", "*a* and *b*", "<i>a</i> and <i>b</i>"},
+		{"unclosed tag", "<b>x", "<b>x</b>"},
+		{"stray closing tag", "x</i>", "x"},
+		{"overlapping tags", "<i>a<b>b</i>c", "<i>a<b>b</b></i>c"},
+		{"code span", "use `a<b && c` here", "use <code>a&lt;b &amp;&amp; c</code> here"},
+		{"no emphasis in code span", "`**not bold**`", "<code>**not bold**</code>"},
+		{"no tags in code", "<code><b>x</b></code>", "<code>&lt;b&gt;x&lt;/b&gt;</code>"},
+		{"code fence", "```\n<b>x</b>\n```", "<pre>&lt;b&gt;x&lt;/b&gt;</pre>"},
+		{"blockquote", "> quote\n> more", "<blockquote>quote\nmore</blockquote>"},
+		{"emphasis in blockquote", "> **q**\nafter", "<blockquote><b>q</b></blockquote>\nafter"},
+		{"expandable blockquote", "<blockquote expandable>q</blockquote>", "<blockquote expandable>q</blockquote>"},
+		{"literal angle brackets and ampersand", "1 < 2 & 3 > 2", "1 &lt; 2 &amp; 3 &gt; 2"},
+		{"known entity", "a &amp; b", "a &amp; b"},
+		{"text that looks like a tag", "<b and c>", "&lt;b and c&gt;"},
+		{"tag next to text that looks like one", "<b>bold</b> <b and c>", "<b>bold</b> &lt;b and c&gt;"},
+		{"unsupported tag", "<script>x</script>", "&lt;script&gt;x&lt;/script&gt;"},
+		{"safe link", `<a href="https://x.y">y</a>`, `<a href="https://x.y">y</a>`},
+		{"unsafe link", `<a href="javascript:x">y</a>`, `&lt;a href="javascript:x"&gt;y`},
+		{"markdown link", "[t](https://e.com)", `<a href="https://e.com">t</a>`},
+		{"heading", "# Head", "<b>Head</b>"},
+		{"bullet", "- item", "• item"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FromMarkdown(tt.in); got != tt.want {
+				t.Errorf("FromMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		limit int
+		want  []string
+	}{
+		{"fits", "<b>a</b>\nb", 3, []string{"<b>a</b>\nb"}},
+		{"at line breaks", "one\ntwo\nthree", 8, []string{"one\ntwo", "three"}},
+		{"multi-line blockquote", "<blockquote>one\ntwo\nthree</blockquote>", 8, []string{"<blockquote>one\ntwo</blockquote>", "<blockquote>three</blockquote>"}},
+		{"multi-line pre", "<pre>a &lt;b&gt;\nc</pre>", 6, []string{"<pre>a &lt;b&gt;</pre>", "<pre>c</pre>"}},
+		{"nested tags", "<i><b>one\ntwo</b></i>", 4, []string{"<i><b>one</b></i>", "<i><b>two</b></i>"}},
+		{"long line not cut inside a tag", `abc <a href="https://x.y">link</a>`, 4, []string{"abc", `<a href="https://x.y">link</a>`}},
+		{"long line not cut inside an entity", "abcd&amp;ef", 5, []string{"abcd&amp;", "ef"}},
+		{"long line keeps emphasis", "<b>abcdefgh</b>", 5, []string{"<b>abcde</b>", "<b>fgh</b>"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Split(tt.in, tt.limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Split(%q, %d) = %q, want %q", tt.in, tt.limit, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Split(%q, %d) = %q, want %q", tt.in, tt.limit, got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
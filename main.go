@@ -61,6 +61,10 @@ func main() {
 		log.Fatalf("Failed to load default chat config: %v", err)
 	}
 
+	if globalCfg.GeminiAPIKey == "" && globalCfg.OpenAIBaseURL == "" && globalCfg.OpenAIAPIKey == "" && globalCfg.OllamaBaseURL == "" {
+		log.Println("Warning: no AI provider is configured. Set GEMINI_API_KEY, OPENAI_BASE_URL/OPENAI_API_KEY or OLLAMA_BASE_URL to enable summaries.")
+	}
+
 	dbStorage, err := storage.NewStorage("newsbot.db")
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
@@ -74,6 +78,11 @@ func main() {
 
 	localizer := localization.NewLocalizer(localeFiles)
 	fetcher := news_fetcher.NewFetcher()
+	if globalCfg.NewsletterSMTPAddr != "" {
+		if err := fetcher.StartNewsletterListener(globalCfg.NewsletterSMTPAddr, globalCfg.NewsletterSMTPDomain, dbStorage); err != nil {
+			log.Fatalf("Failed to start newsletter listener: %v", err)
+		}
+	}
 	appScheduler, err := scheduler.NewScheduler()
 	if err != nil {
 		log.Fatalf("Failed to create scheduler: %v", err)